@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"snapshot/internal/config"
+)
+
+// batchIndex summarises a batch run, written to index.json in the output directory.
+type batchIndex struct {
+	GeneratedAt time.Time     `json:"generatedAt"`
+	Succeeded   int           `json:"succeeded"`
+	Failed      int           `json:"failed"`
+	Users       []batchResult `json:"users"`
+}
+
+// batchResult is the outcome of a single user of a batch run.
+type batchResult struct {
+	Login       string   `json:"login"`
+	OK          bool     `json:"ok"`
+	Output      string   `json:"output"`                // Directory the user's cards were written to, relative to index.json
+	Error       string   `json:"error,omitempty"`       // Why the user failed
+	Unavailable []string `json:"unavailable,omitempty"` // Metrics shown as placeholders on the user's cards
+}
+
+// loginFlags select the users of a command running over several of them.
+type loginFlags struct {
+	users     string
+	usersFile string
+}
+
+func (f *loginFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.users, "users", "", "comma-separated logins (default batch.users or BATCH_USERS)")
+	fs.StringVar(&f.usersFile, "users-file", "", "file with one login per line (default batch.usersFile or BATCH_USERS_FILE)")
+}
+
+// loadUsers loads the configuration of a command running over several users, and the logins it runs over.
+// The token belongs to at most one of the users, so everyone's public data is queried alike.
+func loadUsers(common commonFlags, fetch fetchFlags, users loginFlags) (config.Config, []string, error) {
+	if common.user != "" || common.org != "" {
+		return config.Config{}, nil, errors.New("-user and -org cannot be used here, list the logins with -users or -users-file")
+	}
+	cfg, err := common.load()
+	if err != nil {
+		return config.Config{}, nil, err
+	}
+	if cfg.Organization != "" {
+		return config.Config{}, nil, errors.New("only user snapshots can be generated for several users, unset organization")
+	}
+	fetch.apply(&cfg)
+	cfg.PublicOnly = true
+
+	if users.users != "" {
+		cfg.Batch.Users = nil
+		for _, login := range strings.Split(users.users, ",") {
+			if login = strings.TrimSpace(login); login != "" {
+				cfg.Batch.Users = append(cfg.Batch.Users, login)
+			}
+		}
+	}
+	if users.usersFile != "" {
+		cfg.Batch.UsersFile = users.usersFile
+	}
+
+	logins, err := batchLogins(cfg)
+	if err != nil {
+		return config.Config{}, nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, logins, nil
+}
+
+// batchLogins collects the logins listed in the configuration and the users file, without duplicates.
+func batchLogins(cfg config.Config) ([]string, error) {
+	logins := cfg.Batch.Users
+	if cfg.Batch.UsersFile != "" {
+		fromFile, err := readUsersFile(cfg.Batch.UsersFile)
+		if err != nil {
+			return nil, err
+		}
+		logins = append(logins, fromFile...)
+	}
+
+	// Logins are case-insensitive, the first spelling is kept
+	var unique []string
+	seen := make(map[string]bool)
+	var errs []error
+	for _, login := range logins {
+		if !config.ValidLogin(login) {
+			errs = append(errs, fmt.Errorf("%q is not a valid GitHub login", login))
+			continue
+		}
+		if seen[strings.ToLower(login)] {
+			continue
+		}
+		seen[strings.ToLower(login)] = true
+		unique = append(unique, login)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	if len(unique) == 0 {
+		return nil, errors.New("no users to generate, set batch.users, BATCH_USERS, -users or a users file")
+	}
+	return unique, nil
+}
+
+// readUsersFile reads one login per line, ignoring empty lines and lines starting with #.
+func readUsersFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users file: %w", err)
+	}
+	defer file.Close()
+
+	var logins []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		logins = append(logins, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read users file: %w", err)
+	}
+	return logins, nil
+}
+
+// userConfig is the configuration a single user of a batch is generated with.
+// Their cards, snapshot.json and history go to <output>/<login>, and their lines changed cache next to the configured one.
+func userConfig(cfg config.Config, login string) config.Config {
+	cfg.User = login
+	cfg.Output.Dir = filepath.Join(cfg.Output.Dir, login)
+	cfg.Output.HistoryFile = ""
+	cfg.Output.LinesCacheFile = filepath.Join(filepath.Dir(cfg.Output.LinesCacheFile), login, filepath.Base(cfg.Output.LinesCacheFile))
+	return cfg
+}
+
+func writeBatchIndex(path string, index batchIndex) error {
+	dat, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, dat, 0644)
+}
+
+func runBatch(args []string) error {
+	var common commonFlags
+	var fetch fetchFlags
+	var users loginFlags
+	fs := newFlagSet("batch", "Generate the cards of several users into <output>/<login> from their public data, then write index.json listing the users that succeeded or failed.")
+	common.register(fs)
+	fetch.register(fs)
+	users.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	cfg, logins, err := loadUsers(common, fetch, users)
+	if err != nil {
+		return err
+	}
+	if err := validateCards(cfg); err != nil {
+		return err
+	}
+	if err := validateOutputDir(cfg.Output.Dir); err != nil {
+		return err
+	}
+
+	api, err := newAPIClient(cfg, common, fetch)
+	if err != nil {
+		return err
+	}
+
+	// A failing user is recorded in the index and the batch moves on to the next one
+	index := batchIndex{GeneratedAt: time.Now().UTC(), Users: []batchResult{}}
+	for i, login := range logins {
+		log.Printf("Generating %s (%d/%d)", login, i+1, len(logins))
+
+		result := batchResult{Login: login, Output: login}
+		export, err := generate(api, userConfig(cfg, login))
+		if err != nil {
+			log.Printf("Failed to generate %s: %v", login, err)
+			result.Error = err.Error()
+			index.Failed++
+		} else {
+			result.OK = true
+			result.Unavailable = export.Unavailable
+			index.Succeeded++
+		}
+		index.Users = append(index.Users, result)
+	}
+
+	indexPath := filepath.Join(cfg.Output.Dir, "index.json")
+	if err := writeBatchIndex(indexPath, index); err != nil {
+		return err
+	}
+	log.Printf("Generated %d of %d users, see %s", index.Succeeded, len(logins), indexPath)
+	log.Printf("GitHub API usage: %s", api.limiter.Summary())
+
+	if index.Failed > 0 {
+		return fmt.Errorf("%d of %d users failed, see %s", index.Failed, len(logins), indexPath)
+	}
+	return nil
+}
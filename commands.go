@@ -0,0 +1,488 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"snapshot/internal/config"
+	"snapshot/internal/helpers"
+	"snapshot/internal/history"
+	"snapshot/internal/render"
+	"snapshot/internal/snapshot"
+
+	"gopkg.in/yaml.v3"
+)
+
+// commonFlags are accepted by every command and override the configuration file and environment.
+type commonFlags struct {
+	config    string
+	output    string
+	templates string
+	user      string
+	org       string
+	verbose   bool
+	quiet     bool
+}
+
+func (f *commonFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.config, "config", "", "configuration file (default CONFIG_FILE or "+config.DefaultPath+")")
+	fs.StringVar(&f.output, "output", "", "directory the cards and snapshot.json are written to (default output.dir)")
+	fs.StringVar(&f.templates, "templates", "", "directory of the card templates (default output.templatesDir)")
+	fs.StringVar(&f.user, "user", "", "login the snapshot is generated for (default user or GITHUB_ACTOR)")
+	fs.StringVar(&f.org, "org", "", "organization the snapshot is generated for instead of the user (default organization or ORGANIZATION)")
+	fs.BoolVar(&f.verbose, "v", false, "verbose: also log every API request")
+	fs.BoolVar(&f.quiet, "q", false, "quiet: only print errors")
+}
+
+// load reads the configuration file and environment, then applies the flags on top of them.
+func (f *commonFlags) load() (config.Config, error) {
+	if f.verbose && f.quiet {
+		return config.Config{}, errors.New("-v and -q cannot be used together")
+	}
+	setVerbosity(f.quiet)
+
+	helpers.ReadEnvFile()
+
+	// A configuration file that was asked for explicitly has to exist
+	path := f.config
+	required := path != ""
+	if !required {
+		path = helpers.GetEnv("CONFIG_FILE", config.DefaultPath)
+		required = path != config.DefaultPath
+	}
+	cfg, err := config.Load(path, required)
+	if err != nil {
+		return config.Config{}, err
+	}
+
+	if f.output != "" {
+		cfg.Output.Dir = f.output
+	}
+	if f.templates != "" {
+		cfg.Output.TemplatesDir = f.templates
+	}
+	if f.user != "" {
+		cfg.User = f.user
+	}
+	if f.org != "" {
+		cfg.Organization = f.org
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return config.Config{}, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
+// fetchFlags are accepted by the commands that call the GitHub API.
+type fetchFlags struct {
+	record   bool
+	replay   bool
+	fixtures string
+	public   bool
+}
+
+func (f *fetchFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.record, "record", false, "record every API request and response into the fixtures directory")
+	fs.BoolVar(&f.replay, "replay", false, "answer API requests from the fixtures directory instead of the network")
+	fs.StringVar(&f.fixtures, "fixtures", "", "fixtures directory used by -record and -replay (default output.fixturesDir)")
+	fs.BoolVar(&f.public, "public", false, "only query the user's public data, so the token can belong to someone else (default publicOnly or PUBLIC_ONLY)")
+}
+
+// apply overrides the configuration with the fetch flags that were set.
+func (f *fetchFlags) apply(cfg *config.Config) {
+	if f.fixtures != "" {
+		cfg.Output.FixturesDir = f.fixtures
+	}
+	if f.public {
+		cfg.PublicOnly = true
+	}
+}
+
+func newFlagSet(name string, summary string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: snapshot %s [flags]\n\n%s\n\nFlags:\n", name, summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses the flags of a command, rejecting positional arguments.
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "Unexpected argument %q\n", fs.Arg(0))
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+func renderOptions(cfg config.Config) render.Options {
+	return render.Options{
+		Theme:          cfg.Theme,
+		HeatmapYear:    cfg.Cards.Heatmap.Year,
+		TopReposMetric: cfg.Cards.TopRepos.Metric,
+		TopReposCount:  cfg.Cards.TopRepos.Count,
+	}
+}
+
+// validateCards checks the options the cards are rendered with, which the configuration alone cannot know about.
+func validateCards(cfg config.Config) error {
+	var errs []error
+
+	if err := render.ValidateRepoMetric(cfg.Cards.TopRepos.Metric); err != nil {
+		errs = append(errs, fmt.Errorf("cards.topRepos.metric: %w", err))
+	}
+	if err := render.ValidateLeaderboardMetrics(cfg.Cards.Leaderboard.Metrics); err != nil {
+		errs = append(errs, fmt.Errorf("cards.leaderboard.metrics: %w", err))
+	}
+
+	// Organization snapshots render the organization cards unless cards are listed
+	cards, key := cfg.Cards.Only, "cards.only"
+	if len(cards) == 0 && cfg.Organization != "" {
+		cards, key = render.OrgCards, "organization"
+	}
+
+	entries, err := os.ReadDir(cfg.Output.TemplatesDir)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("output.templatesDir: %w", err))
+	}
+	for _, card := range cards {
+		if !slices.ContainsFunc(entries, func(entry os.DirEntry) bool { return entry.Name() == card }) {
+			errs = append(errs, fmt.Errorf("%s: %s has no template in %s", key, card, cfg.Output.TemplatesDir))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateOutputDir(dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apiClient sends the GitHub API requests of a run.
+// Every snapshot fetched through the same apiClient shares its HTTP client and rate limit budget.
+type apiClient struct {
+	client      *http.Client
+	transport   http.RoundTripper // Used without the token for the downloads that are not API requests, such as avatars and profile views
+	limiter     *helpers.RateLimiter
+	bypassCache bool // Set while recording or replaying, see newAPIClient
+	replay      bool // Set while replaying, the fixture data must not end up in the history
+}
+
+// newAPIClient builds the client every snapshot of the run is fetched with.
+func newAPIClient(cfg config.Config, common commonFlags, fetch fetchFlags) (*apiClient, error) {
+	if fetch.record && fetch.replay {
+		return nil, errors.New("-record and -replay cannot be used together")
+	}
+	fixturesDir := cfg.Output.FixturesDir
+
+	// Replayed runs never reach GitHub, so they do not need a token
+	accessToken, err := helpers.GetRequiredEnv("ACCESS_TOKEN")
+	if fetch.replay {
+		accessToken, err = helpers.GetEnv("ACCESS_TOKEN", helpers.Redacted), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	limiter := helpers.NewRateLimiter()
+	retryPolicy := cfg.RetryPolicy()
+
+	// Fixtures are only reproducible if every repo history is walked in full, so the lines cache is bypassed
+	var transport http.RoundTripper = http.DefaultTransport
+	bypassCache := false
+	switch {
+	case fetch.record:
+		transport = &helpers.RecordTransport{Dir: fixturesDir, Token: accessToken, Transport: transport}
+		bypassCache = true
+		log.Printf("Recording API traffic into %s", fixturesDir)
+	case fetch.replay:
+		transport = &helpers.ReplayTransport{Dir: fixturesDir}
+		bypassCache = true
+		retryPolicy.MaxAttempts = 1
+		log.Printf("Replaying API traffic from %s", fixturesDir)
+	}
+	if common.verbose {
+		transport = &helpers.LogTransport{Transport: transport}
+	}
+
+	client := snapshot.NewClient(snapshot.Options{
+		AccessToken: accessToken,
+		Limiter:     limiter,
+		RetryPolicy: retryPolicy,
+		Transport:   transport,
+	})
+	return &apiClient{client: client, transport: transport, limiter: limiter, bypassCache: bypassCache, replay: fetch.replay}, nil
+}
+
+// fetchSnapshot collects every metric of the configured user or organization from the GitHub API.
+// Only fatal errors are returned, metrics that failed otherwise are listed in the export as unavailable.
+func (api *apiClient) fetchSnapshot(cfg config.Config) (*snapshot.Snapshot, snapshot.Export, error) {
+	if cfg.User == "" && cfg.Organization == "" {
+		return nil, snapshot.Export{}, errors.New("no user has been configured, set GITHUB_ACTOR, user or -user")
+	}
+
+	s := snapshot.NewSnapshot(snapshot.Options{
+		User:                 cfg.User,
+		Organization:         cfg.Organization,
+		Public:               cfg.PublicOnly,
+		Endpoints:            cfg.Endpoints(),
+		ExcludedRepos:        config.Set(cfg.Exclude.Repos),
+		ExcludedLangs:        config.Set(cfg.Exclude.Langs),
+		IncludeForkedRepos:   cfg.Include.ForkedRepos,
+		IncludeExternalRepos: cfg.Include.ExternalRepos,
+		IncludeProfileViews:  cfg.Include.ProfileViews,
+		Workers:              cfg.Fetch.Workers,
+		Transport:            api.transport,
+		Client:               api.client,
+	})
+
+	// Organization snapshots count the lines of every author, so their cache must not be mixed up with a user's
+	owner := cfg.User
+	if cfg.Organization != "" {
+		owner = cfg.Organization
+	}
+	var err error
+	linesCache := snapshot.NewLinesCache(owner)
+	if !cfg.Fetch.LinesFullRescan && !api.bypassCache {
+		linesCache, err = snapshot.LoadLinesCache(cfg.Output.LinesCacheFile, owner)
+		if err != nil {
+			return nil, snapshot.Export{}, err
+		}
+	}
+	snapshot.SetLinesCache(&s, linesCache)
+
+	export, err := snapshot.NewExport(&s)
+	if err != nil {
+		return nil, snapshot.Export{}, fmt.Errorf("failed collecting snapshot: %w", err)
+	}
+	if !api.bypassCache {
+		if err := linesCache.Save(cfg.Output.LinesCacheFile); err != nil {
+			return nil, snapshot.Export{}, err
+		}
+	}
+
+	return &s, export, nil
+}
+
+// generateCards renders the cards of a snapshot, with trends against the history.
+// The heatmap ends on the day the export was generated.
+func generateCards(cfg config.Config, s *snapshot.Snapshot, export snapshot.Export) error {
+	entries, err := history.Load(cfg.HistoryFile())
+	if err != nil {
+		return err
+	}
+	trends := history.Trends(entries, history.NewEntry(export), cfg.Cards.DeltaWindows)
+
+	opts := renderOptions(cfg)
+	opts.Today = export.GeneratedAt
+	data, err := render.NewData(s, opts)
+	if err != nil {
+		return fmt.Errorf("failed collecting snapshot: %w", err)
+	}
+	data = data.WithTrends(trends)
+
+	rendered, err := render.RenderDir(cfg.Output.TemplatesDir, cfg.Output.Dir, cfg.Cards.Only, data)
+	for _, path := range rendered {
+		log.Printf("Generated %s", path)
+	}
+	return err
+}
+
+func exportSnapshot(outputDir string, export snapshot.Export) error {
+	outputPath := filepath.Join(outputDir, "snapshot.json")
+	if err := snapshot.WriteExport(outputPath, export); err != nil {
+		return err
+	}
+	log.Printf("Exported %s", outputPath)
+	return nil
+}
+
+func recordHistory(historyFile string, export snapshot.Export) error {
+	entry := history.NewEntry(export)
+	if err := history.Append(historyFile, entry); err != nil {
+		return err
+	}
+	log.Printf("Recorded metrics for %s in %s", entry.Date, historyFile)
+	return nil
+}
+
+// generate fetches the snapshot of the configured user or organization, renders its cards, exports it and records the history.
+// Replayed runs are for offline debugging, so their old fixture data is not recorded in place of today's entry.
+func generate(api *apiClient, cfg config.Config) (snapshot.Export, error) {
+	if err := validateOutputDir(cfg.Output.Dir); err != nil {
+		return snapshot.Export{}, err
+	}
+
+	s, export, err := api.fetchSnapshot(cfg)
+	if err != nil {
+		return snapshot.Export{}, err
+	}
+
+	if err := generateCards(cfg, s, export); err != nil {
+		return snapshot.Export{}, err
+	}
+	if err := exportSnapshot(cfg.Output.Dir, export); err != nil {
+		return snapshot.Export{}, err
+	}
+	if api.replay {
+		log.Printf("Replayed run, not recording metrics in %s", cfg.HistoryFile())
+		return export, nil
+	}
+	if err := recordHistory(cfg.HistoryFile(), export); err != nil {
+		return snapshot.Export{}, err
+	}
+	return export, nil
+}
+
+func runGenerate(args []string) error {
+	var common commonFlags
+	var fetch fetchFlags
+	fs := newFlagSet("generate", "Fetch the statistics, render every card, write snapshot.json and record the history.")
+	common.register(fs)
+	fetch.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		return err
+	}
+	fetch.apply(&cfg)
+	if err := validateCards(cfg); err != nil {
+		return err
+	}
+
+	api, err := newAPIClient(cfg, common, fetch)
+	if err != nil {
+		return err
+	}
+	if _, err := generate(api, cfg); err != nil {
+		return err
+	}
+
+	log.Printf("GitHub API usage: %s", api.limiter.Summary())
+	return nil
+}
+
+func runFetch(args []string) error {
+	var common commonFlags
+	var fetch fetchFlags
+	fs := newFlagSet("fetch", "Fetch the statistics and write them to snapshot.json, without rendering cards or recording the history.")
+	common.register(fs)
+	fetch.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		return err
+	}
+	fetch.apply(&cfg)
+	if err := validateOutputDir(cfg.Output.Dir); err != nil {
+		return err
+	}
+
+	api, err := newAPIClient(cfg, common, fetch)
+	if err != nil {
+		return err
+	}
+	_, export, err := api.fetchSnapshot(cfg)
+	if err != nil {
+		return err
+	}
+	if err := exportSnapshot(cfg.Output.Dir, export); err != nil {
+		return err
+	}
+
+	log.Printf("GitHub API usage: %s", api.limiter.Summary())
+	return nil
+}
+
+func runRender(args []string) error {
+	var common commonFlags
+	fs := newFlagSet("render", "Render the cards from a snapshot.json saved by generate or fetch, without calling the GitHub API.")
+	common.register(fs)
+	from := fs.String("from", "", "saved snapshot to render (default snapshot.json in the output directory)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		return err
+	}
+	if err := validateCards(cfg); err != nil {
+		return err
+	}
+	if err := validateOutputDir(cfg.Output.Dir); err != nil {
+		return err
+	}
+
+	path := *from
+	if path == "" {
+		path = filepath.Join(cfg.Output.Dir, "snapshot.json")
+	}
+	s, export, err := snapshot.Load(path)
+	if err != nil {
+		return err
+	}
+	if common.user != "" && (export.Organization != nil || !strings.EqualFold(common.user, export.User)) {
+		return fmt.Errorf("%s is a snapshot of %s, not %s", path, export.User, common.user)
+	}
+	if common.org != "" && (export.Organization == nil || !strings.EqualFold(common.org, export.User)) {
+		return fmt.Errorf("%s is a snapshot of %s, not the organization %s", path, export.User, common.org)
+	}
+	log.Printf("Rendering the snapshot of %s generated at %s", export.User, export.GeneratedAt.Format("2006-01-02 15:04 MST"))
+
+	return generateCards(cfg, &s, export)
+}
+
+func runValidateConfig(args []string) error {
+	var common commonFlags
+	fs := newFlagSet("validate-config", "Check the configuration file and environment. With -v the resolved configuration is printed.")
+	common.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	cfg, err := common.load()
+	if err != nil {
+		return err
+	}
+	if err := validateCards(cfg); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	if common.verbose {
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(cfg); err != nil {
+			return err
+		}
+	}
+	if !common.quiet {
+		fmt.Println("Configuration is valid")
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+// Package config loads the snapshot.yaml configuration file and applies environment variable overrides.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"snapshot/internal/helpers"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is the configuration file read when CONFIG_FILE is not set.
+const DefaultPath = "snapshot.yaml"
+
+// Themes are the colour schemes the cards can be rendered in.
+// "auto" renders light cards that switch to dark when embedded with the #gh-dark-mode-only fragment.
+var Themes = []string{"auto", "light", "dark"}
+
+// Config is the schema of snapshot.yaml. Every field can be overridden by the environment variable noted next to it.
+// The access token is only ever read from ACCESS_TOKEN, so it cannot be committed by accident.
+type Config struct {
+	User         string  `yaml:"user"`         // GITHUB_ACTOR
+	Organization string  `yaml:"organization"` // ORGANIZATION, generates an organization snapshot instead of the user's
+	PublicOnly   bool    `yaml:"publicOnly"`   // PUBLIC_ONLY, queries the user's public data so the token can belong to someone else
+	Theme        string  `yaml:"theme"`        // THEME
+	API          API     `yaml:"api"`
+	Exclude      Exclude `yaml:"exclude"`
+	Include      Include `yaml:"include"`
+	Output       Output  `yaml:"output"`
+	Fetch        Fetch   `yaml:"fetch"`
+	Cards        Cards   `yaml:"cards"`
+	Batch        Batch   `yaml:"batch"`
+}
+
+type API struct {
+	URL        string `yaml:"url"`        // API_URL
+	GraphQLURL string `yaml:"graphqlUrl"` // GRAPHQL_URL
+}
+
+type Exclude struct {
+	Repos []string `yaml:"repos"` // EXCLUDED_REPOS, owner/name
+	Langs []string `yaml:"langs"` // EXCLUDED_LANGS
+}
+
+type Include struct {
+	ForkedRepos   bool `yaml:"forkedRepos"`   // INCLUDE_FORKED_REPOS
+	ExternalRepos bool `yaml:"externalRepos"` // INCLUDE_EXTERNAL_REPOS
+	ProfileViews  bool `yaml:"profileViews"`  // INCLUDE_PROFILE_VIEWS
+}
+
+type Output struct {
+	Dir            string `yaml:"dir"`            // OUTPUT_DIR
+	TemplatesDir   string `yaml:"templatesDir"`   // TEMPLATES_DIR
+	HistoryFile    string `yaml:"historyFile"`    // HISTORY_FILE, defaults to history.jsonl inside Dir
+	LinesCacheFile string `yaml:"linesCacheFile"` // LINES_CACHE_FILE
+	FixturesDir    string `yaml:"fixturesDir"`    // FIXTURES_DIR
+}
+
+type Fetch struct {
+	Workers         int   `yaml:"workers"`         // WORKERS
+	LinesFullRescan bool  `yaml:"linesFullRescan"` // LINES_FULL_RESCAN
+	Retry           Retry `yaml:"retry"`
+}
+
+type Retry struct {
+	MaxAttempts int           `yaml:"maxAttempts"` // RETRY_MAX_ATTEMPTS
+	BaseDelay   time.Duration `yaml:"baseDelay"`   // RETRY_BASE_DELAY
+	MaxDelay    time.Duration `yaml:"maxDelay"`    // RETRY_MAX_DELAY
+}
+
+type Cards struct {
+	Only         []string    `yaml:"only"`         // CARDS, template filenames to render, all templates if empty
+	DeltaWindows []int       `yaml:"deltaWindows"` // DELTA_WINDOWS
+	Heatmap      Heatmap     `yaml:"heatmap"`
+	TopRepos     TopRepos    `yaml:"topRepos"`
+	Leaderboard  Leaderboard `yaml:"leaderboard"`
+}
+
+type Batch struct {
+	Users     []string `yaml:"users"`     // BATCH_USERS, logins generated by the batch command
+	UsersFile string   `yaml:"usersFile"` // BATCH_USERS_FILE, file with one more login per line
+}
+
+type Heatmap struct {
+	Year int `yaml:"year"` // HEATMAP_YEAR, 0 for the last 52 weeks
+}
+
+type TopRepos struct {
+	Metric string `yaml:"metric"` // TOP_REPOS_METRIC
+	Count  int    `yaml:"count"`  // TOP_REPOS_COUNT
+}
+
+type Leaderboard struct {
+	Title   string   `yaml:"title"`   // LEADERBOARD_TITLE
+	Metrics []string `yaml:"metrics"` // LEADERBOARD_METRICS, the first ranks the team and the others break ties
+	Count   int      `yaml:"count"`   // LEADERBOARD_COUNT, 0 for the whole team
+}
+
+// Default returns the configuration used when neither the file nor the environment set a value.
+func Default() Config {
+	retry := helpers.DefaultRetryPolicy()
+
+	return Config{
+		Theme: "auto",
+		Output: Output{
+			Dir:            "generated",
+			TemplatesDir:   "templates",
+			LinesCacheFile: filepath.Join(".cache", "lines-changed.json"),
+			FixturesDir:    "fixtures",
+		},
+		Fetch: Fetch{
+			Workers: 8,
+			Retry: Retry{
+				MaxAttempts: retry.MaxAttempts,
+				BaseDelay:   retry.BaseDelay,
+				MaxDelay:    retry.MaxDelay,
+			},
+		},
+		Cards: Cards{
+			DeltaWindows: []int{7},
+			TopRepos:     TopRepos{Metric: "stars", Count: 5},
+			Leaderboard: Leaderboard{
+				Title:   "Team Leaderboard",
+				Metrics: []string{"recentContributions", "mergedPullRequests", "linesChanged"},
+				Count:   10,
+			},
+		},
+	}
+}
+
+// Load reads the configuration file at path on top of the defaults, then applies environment variable overrides.
+// A missing file is only an error if required is set, otherwise the defaults and environment are used on their own.
+func Load(path string, required bool) (Config, error) {
+	cfg := Default()
+
+	dat, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && !required:
+	case err != nil:
+		return Config{}, fmt.Errorf("failed to read config: %w", err)
+	default:
+		if err := decode(dat, &cfg); err != nil {
+			return Config{}, fmt.Errorf("invalid config %s: %w", path, err)
+		}
+	}
+
+	// Environment values that cannot be parsed are reported along with the values out of range
+	envErr := ApplyEnv(&cfg)
+	if err := errors.Join(envErr, cfg.Validate()); err != nil {
+		return Config{}, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
+// decode parses YAML into cfg, rejecting keys that are not part of the schema.
+func decode(dat []byte, cfg *Config) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(dat))
+	decoder.KnownFields(true)
+
+	err := decoder.Decode(cfg)
+	if errors.Is(err, io.EOF) {
+		// An empty file keeps the defaults
+		return nil
+	}
+	return err
+}
+
+// ApplyEnv overrides the values of cfg with every environment variable that is set.
+// Values that cannot be parsed are all reported at once and leave the value of cfg unchanged.
+func ApplyEnv(cfg *Config) error {
+	var errs []error
+	var err error
+	collect := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	cfg.User = helpers.GetEnv("GITHUB_ACTOR", cfg.User)
+	cfg.Organization = helpers.GetEnv("ORGANIZATION", cfg.Organization)
+	cfg.PublicOnly = helpers.GetBooleanEnv("PUBLIC_ONLY", cfg.PublicOnly)
+	cfg.Theme = strings.ToLower(helpers.GetEnv("THEME", cfg.Theme))
+
+	cfg.API.URL = helpers.GetEnv("API_URL", cfg.API.URL)
+	cfg.API.GraphQLURL = helpers.GetEnv("GRAPHQL_URL", cfg.API.GraphQLURL)
+
+	cfg.Exclude.Repos = helpers.GetStringListEnv("EXCLUDED_REPOS", cfg.Exclude.Repos)
+	cfg.Exclude.Langs = helpers.GetStringListEnv("EXCLUDED_LANGS", cfg.Exclude.Langs)
+
+	cfg.Include.ForkedRepos = helpers.GetBooleanEnv("INCLUDE_FORKED_REPOS", cfg.Include.ForkedRepos)
+	cfg.Include.ExternalRepos = helpers.GetBooleanEnv("INCLUDE_EXTERNAL_REPOS", cfg.Include.ExternalRepos)
+	cfg.Include.ProfileViews = helpers.GetBooleanEnv("INCLUDE_PROFILE_VIEWS", cfg.Include.ProfileViews)
+
+	cfg.Output.Dir = helpers.GetEnv("OUTPUT_DIR", cfg.Output.Dir)
+	cfg.Output.TemplatesDir = helpers.GetEnv("TEMPLATES_DIR", cfg.Output.TemplatesDir)
+	cfg.Output.HistoryFile = helpers.GetEnv("HISTORY_FILE", cfg.Output.HistoryFile)
+	cfg.Output.LinesCacheFile = helpers.GetEnv("LINES_CACHE_FILE", cfg.Output.LinesCacheFile)
+	cfg.Output.FixturesDir = helpers.GetEnv("FIXTURES_DIR", cfg.Output.FixturesDir)
+
+	cfg.Fetch.Workers, err = helpers.GetIntEnv("WORKERS", cfg.Fetch.Workers)
+	collect(err)
+	cfg.Fetch.LinesFullRescan = helpers.GetBooleanEnv("LINES_FULL_RESCAN", cfg.Fetch.LinesFullRescan)
+	cfg.Fetch.Retry.MaxAttempts, err = helpers.GetIntEnv("RETRY_MAX_ATTEMPTS", cfg.Fetch.Retry.MaxAttempts)
+	collect(err)
+	cfg.Fetch.Retry.BaseDelay, err = helpers.GetDurationEnv("RETRY_BASE_DELAY", cfg.Fetch.Retry.BaseDelay)
+	collect(err)
+	cfg.Fetch.Retry.MaxDelay, err = helpers.GetDurationEnv("RETRY_MAX_DELAY", cfg.Fetch.Retry.MaxDelay)
+	collect(err)
+
+	cfg.Cards.Only = helpers.GetStringListEnv("CARDS", cfg.Cards.Only)
+	cfg.Cards.DeltaWindows, err = helpers.GetIntListEnv("DELTA_WINDOWS", cfg.Cards.DeltaWindows)
+	collect(err)
+	cfg.Cards.Heatmap.Year, err = helpers.GetIntEnv("HEATMAP_YEAR", cfg.Cards.Heatmap.Year)
+	collect(err)
+	cfg.Cards.TopRepos.Metric = strings.ToLower(helpers.GetEnv("TOP_REPOS_METRIC", cfg.Cards.TopRepos.Metric))
+	cfg.Cards.TopRepos.Count, err = helpers.GetIntEnv("TOP_REPOS_COUNT", cfg.Cards.TopRepos.Count)
+	collect(err)
+	cfg.Cards.Leaderboard.Title = helpers.GetEnv("LEADERBOARD_TITLE", cfg.Cards.Leaderboard.Title)
+	cfg.Cards.Leaderboard.Metrics = helpers.GetStringListEnv("LEADERBOARD_METRICS", cfg.Cards.Leaderboard.Metrics)
+	cfg.Cards.Leaderboard.Count, err = helpers.GetIntEnv("LEADERBOARD_COUNT", cfg.Cards.Leaderboard.Count)
+	collect(err)
+
+	cfg.Batch.Users = helpers.GetStringListEnv("BATCH_USERS", cfg.Batch.Users)
+	cfg.Batch.UsersFile = helpers.GetEnv("BATCH_USERS_FILE", cfg.Batch.UsersFile)
+
+	return errors.Join(errs...)
+}
+
+// Validate reports every value of cfg that is out of range at once.
+func (cfg Config) Validate() error {
+	var errs []error
+
+	if !slices.Contains(Themes, cfg.Theme) {
+		errs = append(errs, fmt.Errorf("theme: unknown theme %q, expected one of %s", cfg.Theme, strings.Join(Themes, ", ")))
+	}
+	for _, repo := range cfg.Exclude.Repos {
+		if _, _, err := helpers.SplitOwnerRepo(repo); err != nil {
+			errs = append(errs, fmt.Errorf("exclude.repos: %q is not in the owner/name form", repo))
+		}
+	}
+	if cfg.Output.Dir == "" {
+		errs = append(errs, errors.New("output.dir: must not be empty"))
+	}
+	if cfg.Output.TemplatesDir == "" {
+		errs = append(errs, errors.New("output.templatesDir: must not be empty"))
+	}
+	if cfg.Fetch.Workers < 1 {
+		errs = append(errs, fmt.Errorf("fetch.workers: must be at least 1, got %d", cfg.Fetch.Workers))
+	}
+	if cfg.Fetch.Retry.MaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("fetch.retry.maxAttempts: must be at least 1, got %d", cfg.Fetch.Retry.MaxAttempts))
+	}
+	if cfg.Fetch.Retry.BaseDelay < 0 || cfg.Fetch.Retry.MaxDelay < cfg.Fetch.Retry.BaseDelay {
+		errs = append(errs, fmt.Errorf("fetch.retry: delays must satisfy 0 <= baseDelay <= maxDelay, got %s and %s", cfg.Fetch.Retry.BaseDelay, cfg.Fetch.Retry.MaxDelay))
+	}
+	for _, days := range cfg.Cards.DeltaWindows {
+		if days <= 0 {
+			errs = append(errs, fmt.Errorf("cards.deltaWindows: windows must be positive, got %d", days))
+		}
+	}
+	if cfg.Cards.TopRepos.Count < 0 {
+		errs = append(errs, fmt.Errorf("cards.topRepos.count: must not be negative, got %d", cfg.Cards.TopRepos.Count))
+	}
+	if cfg.Cards.Leaderboard.Count < 0 {
+		errs = append(errs, fmt.Errorf("cards.leaderboard.count: must not be negative, got %d", cfg.Cards.Leaderboard.Count))
+	}
+	for _, login := range cfg.Batch.Users {
+		if !ValidLogin(login) {
+			errs = append(errs, fmt.Errorf("batch.users: %q is not a valid GitHub login", login))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// loginPattern matches GitHub logins: alphanumerics and single hyphens, neither leading nor trailing.
+var loginPattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9]|-[A-Za-z0-9]){0,38}$`)
+
+// ValidLogin reports whether login is a valid GitHub login, and so safe to use as a directory name.
+func ValidLogin(login string) bool {
+	return loginPattern.MatchString(login)
+}
+
+// HistoryFile returns the history file, falling back to history.jsonl inside the output directory.
+func (cfg Config) HistoryFile() string {
+	if cfg.Output.HistoryFile != "" {
+		return cfg.Output.HistoryFile
+	}
+	return filepath.Join(cfg.Output.Dir, "history.jsonl")
+}
+
+// Endpoints returns the GitHub API endpoints described by the api section.
+func (cfg Config) Endpoints() helpers.Endpoints {
+	return helpers.NewEndpoints(cfg.API.URL, cfg.API.GraphQLURL)
+}
+
+// RetryPolicy returns the retry policy described by the fetch.retry section.
+func (cfg Config) RetryPolicy() helpers.RetryPolicy {
+	policy := helpers.DefaultRetryPolicy()
+	policy.MaxAttempts = cfg.Fetch.Retry.MaxAttempts
+	policy.BaseDelay = cfg.Fetch.Retry.BaseDelay
+	policy.MaxDelay = cfg.Fetch.Retry.MaxDelay
+	return policy
+}
+
+// Set converts a list of names into the lower case set the snapshot filters on.
+func Set(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		set[strings.ToLower(strings.TrimSpace(value))] = struct{}{}
+	}
+	return set
+}
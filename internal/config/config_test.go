@@ -0,0 +1,197 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets every override for the duration of the test, e.g. GITHUB_ACTOR when running in GitHub Actions.
+func clearEnv(t *testing.T) {
+	for _, name := range []string{
+		"GITHUB_ACTOR", "ORGANIZATION", "PUBLIC_ONLY", "THEME", "API_URL", "GRAPHQL_URL", "EXCLUDED_REPOS", "EXCLUDED_LANGS",
+		"INCLUDE_FORKED_REPOS", "INCLUDE_EXTERNAL_REPOS", "INCLUDE_PROFILE_VIEWS",
+		"OUTPUT_DIR", "TEMPLATES_DIR", "HISTORY_FILE", "LINES_CACHE_FILE", "FIXTURES_DIR",
+		"WORKERS", "LINES_FULL_RESCAN", "RETRY_MAX_ATTEMPTS", "RETRY_BASE_DELAY", "RETRY_MAX_DELAY",
+		"CARDS", "DELTA_WINDOWS", "HEATMAP_YEAR", "TOP_REPOS_METRIC", "TOP_REPOS_COUNT",
+		"LEADERBOARD_TITLE", "LEADERBOARD_METRICS", "LEADERBOARD_COUNT",
+		"BATCH_USERS", "BATCH_USERS_FILE",
+	} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "snapshot.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadExample(t *testing.T) {
+	clearEnv(t)
+	cfg, err := Load("../../snapshot.example.yaml", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The example documents the defaults, apart from the derived values it spells out
+	want := Default()
+	want.API.URL = "https://api.github.com"
+	want.Exclude = Exclude{Repos: []string{}, Langs: []string{}}
+	want.Cards.Only = []string{}
+	want.Batch.Users = []string{}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("example config = %+v, want the defaults %+v", cfg, want)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	clearEnv(t)
+	path := filepath.Join(t.TempDir(), "snapshot.yaml")
+
+	if _, err := Load(path, false); err != nil {
+		t.Errorf("optional missing config: %v", err)
+	}
+	if _, err := Load(path, true); err == nil {
+		t.Error("expected an error for a required missing config")
+	}
+}
+
+func TestEnvOverridesFile(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, `
+user: octocat
+theme: dark
+exclude:
+  repos: [octocat/a, octocat/b]
+fetch:
+  retry:
+    baseDelay: 2s
+cards:
+  topRepos:
+    count: 3
+`)
+	t.Setenv("GITHUB_ACTOR", "hubot")
+	t.Setenv("EXCLUDED_REPOS", "hubot/c")
+	t.Setenv("TOP_REPOS_COUNT", "10")
+
+	cfg, err := Load(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.User != "hubot" {
+		t.Errorf("user = %q, want the environment value", cfg.User)
+	}
+	if cfg.Theme != "dark" {
+		t.Errorf("theme = %q, want the file value", cfg.Theme)
+	}
+	if !reflect.DeepEqual(cfg.Exclude.Repos, []string{"hubot/c"}) {
+		t.Errorf("exclude.repos = %v, want the environment value", cfg.Exclude.Repos)
+	}
+	if cfg.Fetch.Retry.BaseDelay != 2*time.Second {
+		t.Errorf("fetch.retry.baseDelay = %s, want 2s", cfg.Fetch.Retry.BaseDelay)
+	}
+	if cfg.Cards.TopRepos.Count != 10 {
+		t.Errorf("cards.topRepos.count = %d, want 10", cfg.Cards.TopRepos.Count)
+	}
+}
+
+func TestEmptyEnvKeepsFile(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, `
+include:
+  forkedRepos: true
+exclude:
+  repos: [octocat/a]
+cards:
+  deltaWindows: [30]
+  topRepos:
+    metric: forks
+`)
+
+	// The workflow passes secrets that are not set as empty values
+	for _, name := range []string{"INCLUDE_FORKED_REPOS", "EXCLUDED_REPOS", "DELTA_WINDOWS", "TOP_REPOS_METRIC", "TOP_REPOS_COUNT", "WORKERS"} {
+		t.Setenv(name, "")
+	}
+
+	cfg, err := Load(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cfg.Include.ForkedRepos || !reflect.DeepEqual(cfg.Exclude.Repos, []string{"octocat/a"}) {
+		t.Errorf("include, exclude = %+v, %+v, want the file values", cfg.Include, cfg.Exclude)
+	}
+	if !reflect.DeepEqual(cfg.Cards.DeltaWindows, []int{30}) || cfg.Cards.TopRepos != (TopRepos{Metric: "forks", Count: 5}) {
+		t.Errorf("cards = %+v, want the file values and default count", cfg.Cards)
+	}
+	if cfg.Fetch.Workers != 8 {
+		t.Errorf("fetch.workers = %d, want the default", cfg.Fetch.Workers)
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	path := writeConfig(t, `
+user: octocat
+exclude:
+  repositories: [octocat/a]
+`)
+
+	_, err := Load(path, true)
+	if err == nil || !strings.Contains(err.Error(), "line 4: field repositories not found") {
+		t.Errorf("error = %v, want the unknown key and its line", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, `
+theme: neon
+exclude:
+  repos: [not-a-repo]
+fetch:
+  workers: 0
+cards:
+  leaderboard:
+    count: -1
+batch:
+  users: [octocat, ../etc]
+`)
+
+	_, err := Load(path, true)
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"theme", "exclude.repos", "fetch.workers", "cards.leaderboard.count", "batch.users"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestInvalidEnv(t *testing.T) {
+	clearEnv(t)
+	path := writeConfig(t, "theme: neon\n")
+	t.Setenv("WORKERS", "abc")
+	t.Setenv("DELTA_WINDOWS", "7,-7")
+	t.Setenv("RETRY_BASE_DELAY", "soon")
+
+	// Invalid environment values are reported with the invalid file values instead of falling back to a default
+	_, err := Load(path, true)
+	if err == nil {
+		t.Fatal("expected errors for the invalid environment values")
+	}
+	for _, want := range []string{"theme", "WORKERS", "cards.deltaWindows", "RETRY_BASE_DELAY"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
@@ -0,0 +1,495 @@
+// Package githubtest serves canned GitHub GraphQL and REST responses from an httptest server,
+// so the snapshot can be exercised without a token or network access.
+package githubtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"snapshot/internal/helpers"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Repo is a repository served by the fake GitHub.
+type Repo struct {
+	NameWithOwner string
+	IsFork        bool
+	Private       bool // Left out of queries with privacy: PUBLIC
+	External      bool // Listed under repositoriesContributedTo instead of the user's own repositories
+	Stars         int
+	Forks         int
+	Languages     []Language
+	Commits       []Commit // Default branch history, newest first
+	Views         int
+	NoTraffic     bool // Traffic views answer 403, as they do without push access
+}
+
+// Language is a language of a served repository.
+type Language struct {
+	Name  string
+	Color string
+	Size  int
+}
+
+// Commit is a commit on the default branch of a served repository.
+type Commit struct {
+	Oid       string
+	Author    string // Login of the author, empty if the author has no GitHub account
+	Additions int
+	Deletions int
+	Merge     bool // Has a second parent
+}
+
+// Day is a single day of a contribution calendar.
+type Day struct {
+	Date  string // YYYY-MM-DD
+	Count int
+	Level string
+}
+
+// Year is the contributionsCollection of a single contribution year.
+type Year struct {
+	PullRequests int
+	Issues       int
+	Reviews      int
+	Days         []Day
+}
+
+// Server is a fake GitHub. Its fields can be changed between requests, but not during one.
+type Server struct {
+	*httptest.Server
+
+	Token              string // If set, API requests without it are answered with 401
+	Login              string
+	Name               string
+	Repos              []Repo
+	Years              map[int]Year
+	MergedPullRequests int
+	ClosedIssues       int
+	ProfileViews       int
+	PageSize           int  // Nodes returned per page of repositories and commit history, 100 if unset
+	FailProfileViews   bool // Profile views answer 503
+	OrgName            string
+	OrgDescription     string
+	FailContributors   bool // Repository contributors answer 500
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+// NewServer starts a fake GitHub for the given login. It is closed when the test ends.
+// The repositories are also served as those of any organization that is queried, forks included.
+func NewServer(t interface{ Cleanup(func()) }, login string) *Server {
+	s := &Server{
+		Login:    login,
+		Years:    make(map[int]Year),
+		requests: make(map[string]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /graphql", s.authorized(s.serveGraphQL))
+	mux.HandleFunc("GET /repos/{owner}/{name}/traffic/views", s.authorized(s.serveViews))
+	mux.HandleFunc("GET /repos/{owner}/{name}/contributors", s.authorized(s.serveContributors))
+	mux.HandleFunc("GET /avatars/{login}", s.serveAvatar)
+	mux.HandleFunc("GET /ghpvc/", s.serveProfileViews)
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Endpoints returns the endpoints pointing every API at the fake server.
+func (s *Server) Endpoints() helpers.Endpoints {
+	return helpers.Endpoints{
+		REST:         s.URL,
+		GraphQL:      s.URL + "/graphql",
+		ProfileViews: s.URL + "/ghpvc/",
+	}
+}
+
+// Requests returns how many requests of a kind were served: "repositories", "organization", "history", "contributionYears",
+// "contributions", "views", "contributors", "avatars" or "profileViews".
+func (s *Server) Requests(kind string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[kind]
+}
+
+func (s *Server) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.Token != "" && r.Header.Get("Authorization") != "Bearer "+s.Token {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"message": "Bad credentials"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) count(kind string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[kind]++
+}
+
+func (s *Server) pageSize() int {
+	if s.PageSize > 0 {
+		return s.PageSize
+	}
+	return 100
+}
+
+func (s *Server) repo(nameWithOwner string) *Repo {
+	for i := range s.Repos {
+		if s.Repos[i].NameWithOwner == nameWithOwner {
+			return &s.Repos[i]
+		}
+	}
+	return nil
+}
+
+// page slices items from the offset encoded in cursor, returning the page and its pageInfo.
+func page[T any](items []T, cursor any, size int) ([]T, map[string]any) {
+	start := 0
+	if c, ok := cursor.(string); ok && c != "" {
+		start, _ = strconv.Atoi(c)
+	}
+	start = min(start, len(items))
+	end := min(start+size, len(items))
+
+	return items[start:end], map[string]any{
+		"hasNextPage": end < len(items),
+		"endCursor":   strconv.Itoa(end),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+var (
+	yearAlias = regexp.MustCompile(`year(\d+):\s*contributionsCollection`)
+	userLogin = regexp.MustCompile(`user\(login:\s*"([^"]*)"\)`)
+)
+
+// queriedUser returns the login a query asks for through user(login:), if it does not query the viewer.
+func queriedUser(query string, vars map[string]any) (string, bool) {
+	if match := userLogin.FindStringSubmatch(query); match != nil {
+		return match[1], true
+	}
+	if strings.Contains(query, "user(login:") {
+		return fmt.Sprint(vars["login"]), true
+	}
+	return "", false
+}
+
+func (s *Server) serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query     string         `json:"query"`
+		Variables map[string]any `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"message": err.Error()})
+		return
+	}
+
+	// Only the server's own user can be queried through user(login:), like a login that does not exist otherwise
+	root := "viewer"
+	if login, ok := queriedUser(req.Query, req.Variables); ok {
+		if login != s.Login {
+			writeJSON(w, http.StatusOK, map[string]any{
+				"data":   map[string]any{"user": nil},
+				"errors": []any{map[string]any{"type": "NOT_FOUND", "message": fmt.Sprintf("Could not resolve to a User with the login of '%s'.", login)}},
+			})
+			return
+		}
+		root = "user"
+	}
+
+	var data map[string]any
+	switch {
+	case strings.Contains(req.Query, "contributionYears"):
+		s.count("contributionYears")
+		data = map[string]any{root: map[string]any{"contributionsCollection": map[string]any{"contributionYears": s.contributionYears()}}}
+	case strings.Contains(req.Query, "history("):
+		s.count("history")
+		data = s.history(req.Variables)
+	case strings.Contains(req.Query, "organization("):
+		s.count("organization")
+		data = s.organization(req.Variables)
+	case strings.Contains(req.Query, "repositoriesContributedTo"):
+		s.count("repositories")
+		data = s.repositories(root, req.Variables, strings.Contains(req.Query, "privacy: PUBLIC"))
+	case yearAlias.MatchString(req.Query):
+		s.count("contributions")
+		data = s.contributions(req.Query)
+	default:
+		writeJSON(w, http.StatusOK, map[string]any{"errors": []any{map[string]any{"message": "unknown query"}}})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"data": data})
+}
+
+func (s *Server) contributionYears() []int {
+	years := make([]int, 0, len(s.Years))
+	for year := range s.Years {
+		years = append(years, year)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(years)))
+	return years
+}
+
+func repoNode(repo Repo) map[string]any {
+	edges := make([]any, 0, len(repo.Languages))
+	primary := map[string]any{"name": "", "color": ""}
+	for i, lang := range repo.Languages {
+		edges = append(edges, map[string]any{
+			"size": lang.Size,
+			"node": map[string]any{"name": lang.Name, "color": lang.Color},
+		})
+		if i == 0 {
+			primary = map[string]any{"name": lang.Name, "color": lang.Color}
+		}
+	}
+
+	return map[string]any{
+		"nameWithOwner":   repo.NameWithOwner,
+		"isFork":          repo.IsFork,
+		"stargazers":      map[string]any{"totalCount": repo.Stars},
+		"forkCount":       repo.Forks,
+		"primaryLanguage": primary,
+		"languages":       map[string]any{"edges": edges},
+	}
+}
+
+func (s *Server) repositories(root string, vars map[string]any, public bool) map[string]any {
+	// Like GitHub, the user's own repositories are queried with isFork: false
+	var owned, external []map[string]any
+	for _, repo := range s.Repos {
+		switch {
+		case repo.Private && public:
+		case repo.External:
+			external = append(external, repoNode(repo))
+		case !repo.IsFork:
+			owned = append(owned, repoNode(repo))
+		}
+	}
+
+	ownedPage, ownedInfo := page(owned, vars["repoCursor"], s.pageSize())
+	externalPage, externalInfo := page(external, vars["contribCursor"], s.pageSize())
+
+	return map[string]any{root: map[string]any{
+		"login":                     s.Login,
+		"name":                      s.Name,
+		"avatarUrl":                 s.URL + "/avatars/" + s.Login,
+		"repositories":              map[string]any{"pageInfo": ownedInfo, "nodes": nonNil(ownedPage)},
+		"repositoriesContributedTo": map[string]any{"pageInfo": externalInfo, "nodes": nonNil(externalPage)},
+	}}
+}
+
+func (s *Server) organization(vars map[string]any) map[string]any {
+	// Unlike the user's own repositories, the repositories of an organization include forks
+	var repos []map[string]any
+	for _, repo := range s.Repos {
+		if !repo.External {
+			repos = append(repos, repoNode(repo))
+		}
+	}
+	nodes, info := page(repos, vars["repoCursor"], s.pageSize())
+
+	login := fmt.Sprint(vars["login"])
+	return map[string]any{"organization": map[string]any{
+		"login":        login,
+		"name":         s.OrgName,
+		"description":  s.OrgDescription,
+		"avatarUrl":    s.URL + "/avatars/" + login,
+		"repositories": map[string]any{"pageInfo": info, "nodes": nonNil(nodes)},
+	}}
+}
+
+func (s *Server) history(vars map[string]any) map[string]any {
+	repo := s.repo(fmt.Sprintf("%v/%v", vars["owner"], vars["name"]))
+	if repo == nil {
+		return map[string]any{"repository": nil}
+	}
+
+	commits, info := page(repo.Commits, vars["commitCursor"], s.pageSize())
+	nodes := make([]any, 0, len(commits))
+	for _, commit := range commits {
+		var user any
+		if commit.Author != "" {
+			user = map[string]any{"login": commit.Author}
+		}
+		parents := 1
+		if commit.Merge {
+			parents = 2
+		}
+		nodes = append(nodes, map[string]any{
+			"oid":       commit.Oid,
+			"additions": commit.Additions,
+			"deletions": commit.Deletions,
+			"author":    map[string]any{"user": user},
+			"parents":   map[string]any{"totalCount": parents},
+		})
+	}
+
+	return map[string]any{"repository": map[string]any{
+		"defaultBranchRef": map[string]any{
+			"target": map[string]any{
+				"history": map[string]any{"pageInfo": info, "nodes": nodes},
+			},
+		},
+	}}
+}
+
+func (s *Server) contributions(query string) map[string]any {
+	viewer := make(map[string]any)
+	for _, match := range yearAlias.FindAllStringSubmatch(query, -1) {
+		year, _ := strconv.Atoi(match[1])
+		y := s.Years[year]
+
+		total := 0
+		var weeks []any
+		var days []any
+		for i, day := range y.Days {
+			total += day.Count
+			days = append(days, map[string]any{"date": day.Date, "contributionCount": day.Count, "contributionLevel": day.Level})
+			if len(days) == 7 || i == len(y.Days)-1 {
+				weeks = append(weeks, map[string]any{"contributionDays": days})
+				days = nil
+			}
+		}
+
+		viewer["year"+match[1]] = map[string]any{
+			"totalPullRequestContributions":       y.PullRequests,
+			"totalIssueContributions":             y.Issues,
+			"totalPullRequestReviewContributions": y.Reviews,
+			"contributionCalendar": map[string]any{
+				"totalContributions": total,
+				"weeks":              nonNil(weeks),
+			},
+		}
+	}
+
+	return map[string]any{
+		"viewer":             viewer,
+		"mergedPullRequests": map[string]any{"issueCount": s.MergedPullRequests},
+		"closedIssues":       map[string]any{"issueCount": s.ClosedIssues},
+	}
+}
+
+func (s *Server) serveViews(w http.ResponseWriter, r *http.Request) {
+	s.count("views")
+
+	repo := s.repo(r.PathValue("owner") + "/" + r.PathValue("name"))
+	if repo == nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"message": "Not Found"})
+		return
+	}
+	if repo.NoTraffic {
+		writeJSON(w, http.StatusForbidden, map[string]any{"message": "Must have push access to repository"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"count": repo.Views, "uniques": repo.Views, "views": []any{}})
+}
+
+// serveContributors lists the authors of a repository's commits by number of commits, like GitHub.
+// Commits without a GitHub account are left out and repositories without commits answer 204.
+func (s *Server) serveContributors(w http.ResponseWriter, r *http.Request) {
+	s.count("contributors")
+
+	repo := s.repo(r.PathValue("owner") + "/" + r.PathValue("name"))
+	if repo == nil {
+		writeJSON(w, http.StatusNotFound, map[string]any{"message": "Not Found"})
+		return
+	}
+	if s.FailContributors {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"message": "Server Error"})
+		return
+	}
+	if len(repo.Commits) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	byLogin := make(map[string]int)
+	for _, commit := range repo.Commits {
+		if commit.Author != "" {
+			byLogin[commit.Author]++
+		}
+	}
+	contributors := make([]map[string]any, 0, len(byLogin))
+	for login, count := range byLogin {
+		contributors = append(contributors, map[string]any{"login": login, "contributions": count, "type": "User"})
+	}
+	sort.Slice(contributors, func(i, j int) bool {
+		ci, cj := contributors[i]["contributions"].(int), contributors[j]["contributions"].(int)
+		if ci != cj {
+			return ci > cj
+		}
+		return contributors[i]["login"].(string) < contributors[j]["login"].(string)
+	})
+
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage <= 0 {
+		perPage = 30
+	}
+	pageNumber, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	pageNumber = max(pageNumber, 1)
+	contributors, _ = page(contributors, strconv.Itoa((pageNumber-1)*perPage), perPage)
+
+	writeJSON(w, http.StatusOK, contributors)
+}
+
+// Avatar is the image served for every avatar.
+var Avatar = []byte("\x89PNG\r\n\x1a\nfake avatar")
+
+func (s *Server) serveAvatar(w http.ResponseWriter, r *http.Request) {
+	s.count("avatars")
+
+	// Avatars are served by another host than the API, which must not receive the token
+	if r.Header.Get("Authorization") != "" {
+		http.Error(w, "unexpected credentials", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	_, _ = w.Write(Avatar)
+}
+
+func (s *Server) serveProfileViews(w http.ResponseWriter, r *http.Request) {
+	s.count("profileViews")
+
+	// The counter is served by another host than the API, which must not receive the token
+	if r.Header.Get("Authorization") != "" {
+		http.Error(w, "unexpected credentials", http.StatusBadRequest)
+		return
+	}
+
+	if s.FailProfileViews || r.URL.Query().Get("username") != s.Login {
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg"><g><text>Profile views</text><text>Profile views</text></g><g><text>%s</text><text>%s</text></g></svg>`,
+		humanize.Comma(int64(s.ProfileViews)), humanize.Comma(int64(s.ProfileViews)))
+}
+
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,24 @@
+package helpers
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// LogTransport logs every request it sends with the response status and how long it took.
+type LogTransport struct {
+	Transport http.RoundTripper
+}
+
+func (t *LogTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.Transport.RoundTrip(req)
+	if err != nil {
+		log.Printf("%s %s failed after %s: %v", req.Method, req.URL.Redacted(), time.Since(start).Round(time.Millisecond), err)
+		return nil, err
+	}
+
+	log.Printf("%s %s -> %d in %s", req.Method, req.URL.Redacted(), resp.StatusCode, time.Since(start).Round(time.Millisecond))
+	return resp, nil
+}
@@ -1,9 +1,11 @@
 package helpers
 
 import (
-	"log"
+	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -18,12 +20,22 @@ func GetRequiredEnv(name string) (string, error) {
 	value, valueExists := os.LookupEnv(name)
 
 	if !valueExists || value == "" {
-		log.Fatalf("No %s has been configured.", name)
+		return "", fmt.Errorf("no %s has been configured", name)
 	}
 
 	return value, nil
 }
 
+func GetEnv(name string, defaultValue string) string {
+	value, valueExists := os.LookupEnv(name)
+
+	if !valueExists || strings.TrimSpace(value) == "" {
+		return defaultValue
+	}
+
+	return strings.TrimSpace(value)
+}
+
 func GetListEnv(name string) (valueList map[string]struct{}) {
 	value, valueExists := os.LookupEnv(name)
 
@@ -46,9 +58,80 @@ func GetListEnv(name string) (valueList map[string]struct{}) {
 func GetBooleanEnv(name string, defaultValue bool) bool {
 	value, valueExists := os.LookupEnv(name)
 
-	if valueExists {
-		return strings.ToLower(strings.TrimSpace(value)) != "false"
+	if !valueExists || strings.TrimSpace(value) == "" {
+		return defaultValue
+	}
+
+	return strings.ToLower(strings.TrimSpace(value)) != "false"
+}
+
+// GetIntEnv returns the integer in name, or defaultValue along with an error if it is not a valid integer.
+func GetIntEnv(name string, defaultValue int) (int, error) {
+	value, valueExists := os.LookupEnv(name)
+
+	if !valueExists || strings.TrimSpace(value) == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return defaultValue, fmt.Errorf("%s: %q is not an integer", name, value)
+	}
+
+	return parsed, nil
+}
+
+// GetDurationEnv returns the duration in name, e.g. 2s, or defaultValue along with an error if it cannot be parsed.
+func GetDurationEnv(name string, defaultValue time.Duration) (time.Duration, error) {
+	value, valueExists := os.LookupEnv(name)
+
+	if !valueExists || strings.TrimSpace(value) == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return defaultValue, fmt.Errorf("%s: %q is not a duration such as 2s", name, value)
+	}
+
+	return parsed, nil
+}
+
+// GetIntListEnv returns the comma-separated integers in name, or defaultValue along with an error if one is not an integer.
+// The range of the values is left to the caller, so it can report them like the configuration file's.
+func GetIntListEnv(name string, defaultValue []int) ([]int, error) {
+	value, valueExists := os.LookupEnv(name)
+
+	if !valueExists || strings.TrimSpace(value) == "" {
+		return defaultValue, nil
+	}
+
+	var valueList []int
+	for _, v := range strings.Split(value, ",") {
+		parsed, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return defaultValue, fmt.Errorf("%s: %q is not an integer", name, strings.TrimSpace(v))
+		}
+		valueList = append(valueList, parsed)
+	}
+
+	return valueList, nil
+}
+
+func GetStringListEnv(name string, defaultValue []string) []string {
+	value, valueExists := os.LookupEnv(name)
+
+	if !valueExists || strings.TrimSpace(value) == "" {
+		return defaultValue
+	}
+
+	valueList := []string{}
+	for _, v := range strings.Split(value, ",") {
+		parsed := strings.TrimSpace(v)
+		if parsed != "" {
+			valueList = append(valueList, parsed)
+		}
 	}
 
-	return defaultValue
+	return valueList
 }
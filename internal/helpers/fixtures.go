@@ -0,0 +1,207 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces the access token wherever it appears in a recorded fixture.
+const Redacted = "REDACTED"
+
+// Fixture is a single recorded request and the response it received.
+type Fixture struct {
+	Request  FixtureRequest  `json:"request"`
+	Response FixtureResponse `json:"response"`
+}
+
+type FixtureRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type FixtureResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// RecordTransport saves every request and its response as a fixture in Dir, with Token scrubbed.
+// It sits at the bottom of the transport chain, so each attempt of a retried request is recorded and the last one is kept.
+type RecordTransport struct {
+	Dir       string
+	Token     string
+	Transport http.RoundTripper
+
+	mu sync.Mutex
+}
+
+// ReplayTransport answers requests with the fixtures recorded in Dir without touching the network.
+// A request that was never recorded fails with an error.
+type ReplayTransport struct {
+	Dir string
+}
+
+func (t *RecordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	fixture := Fixture{
+		Request: FixtureRequest{
+			Method: req.Method,
+			URL:    t.scrub(req.URL.String()),
+			Header: t.scrubHeader(req.Header),
+			Body:   t.scrub(string(reqBody)),
+		},
+		Response: FixtureResponse{
+			StatusCode: resp.StatusCode,
+			Header:     t.scrubHeader(resp.Header),
+			Body:       t.scrub(string(respBody)),
+		},
+	}
+
+	if err := t.save(fixtureName(req.Method, req.URL.String(), reqBody), fixture); err != nil {
+		return nil, fmt.Errorf("failed to record %s: %w", req.URL.Path, err)
+	}
+	return resp, nil
+}
+
+func (t *RecordTransport) save(name string, fixture Fixture) error {
+	dat, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := os.MkdirAll(t.Dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(t.Dir, name), dat, 0644)
+}
+
+func (t *RecordTransport) scrub(s string) string {
+	if t.Token == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, t.Token, Redacted)
+}
+
+func (t *RecordTransport) scrubHeader(header http.Header) http.Header {
+	scrubbed := make(http.Header, len(header))
+	for key, values := range header {
+		if strings.EqualFold(key, "Authorization") {
+			scrubbed[key] = []string{Redacted}
+			continue
+		}
+		for _, value := range values {
+			scrubbed[key] = append(scrubbed[key], t.scrub(value))
+		}
+	}
+	return scrubbed
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	name := fixtureName(req.Method, req.URL.String(), reqBody)
+	dat, err := os.ReadFile(filepath.Join(t.Dir, name))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no fixture recorded for %s %s (%s)", req.Method, req.URL, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture Fixture
+	if err := json.Unmarshal(dat, &fixture); err != nil {
+		return nil, fmt.Errorf("failed to decode fixture %s: %w", name, err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.Response.StatusCode, http.StatusText(fixture.Response.StatusCode)),
+		StatusCode:    fixture.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        fixture.Response.Header,
+		Body:          io.NopCloser(strings.NewReader(fixture.Response.Body)),
+		ContentLength: int64(len(fixture.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// readRequestBody reads the body of a request and puts it back so it can still be sent.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+var unsafeFixtureChars = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// fixtureName derives a stable file name from a request, so replaying the same request finds its recording.
+// It starts with the request path for readability and ends with a hash of the method, URL and body.
+func fixtureName(method string, url string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + "\n" + url + "\n"))
+	hash.Write(body)
+	sum := hex.EncodeToString(hash.Sum(nil))[:16]
+
+	path := url
+	if i := strings.Index(path, "://"); i >= 0 {
+		path = path[i+3:]
+	}
+	if i := strings.Index(path, "/"); i >= 0 {
+		path = path[i+1:]
+	}
+	if i := strings.Index(path, "?"); i >= 0 {
+		path = path[:i]
+	}
+	path = strings.Trim(unsafeFixtureChars.ReplaceAllString(path, "-"), "-")
+	if len(path) > 60 {
+		path = path[:60]
+	}
+	if path == "" {
+		path = "root"
+	}
+
+	return fmt.Sprintf("%s-%s-%s.json", strings.ToLower(method), path, sum)
+}
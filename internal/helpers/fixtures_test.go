@@ -0,0 +1,63 @@
+package helpers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	const token = "secret-token"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		body, _ := io.ReadAll(r.Body)
+		io.WriteString(w, `{"echo":"`+r.URL.Path+`","body":"`+string(body)+`","auth":"`+r.Header.Get("Authorization")+`"}`)
+	}))
+	dir := t.TempDir()
+
+	record := &http.Client{Transport: &TransportWithToken{
+		Token:     token,
+		Transport: &RecordTransport{Dir: dir, Token: token, Transport: http.DefaultTransport},
+	}}
+	recorded := send(t, record, server.URL+"/graphql", "query")
+	server.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("recorded %d fixtures, want 1", len(files))
+	}
+	dat, _ := os.ReadFile(files[0])
+	if strings.Contains(string(dat), token) {
+		t.Errorf("fixture contains the token:\n%s", dat)
+	}
+
+	replay := &http.Client{Transport: &TransportWithToken{
+		Token:     "another-token",
+		Transport: &ReplayTransport{Dir: dir},
+	}}
+	replayed := send(t, replay, server.URL+"/graphql", "query")
+	if want := strings.ReplaceAll(recorded, token, Redacted); replayed != want {
+		t.Errorf("replayed %s, want %s", replayed, want)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/graphql", strings.NewReader("other query"))
+	if _, err := replay.Do(req); err == nil {
+		t.Error("expected an error for a request that was never recorded")
+	}
+}
+
+func send(t *testing.T, client *http.Client, url string, body string) string {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	dat, _ := io.ReadAll(resp.Body)
+	return string(dat)
+}
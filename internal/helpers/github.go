@@ -14,3 +14,30 @@ func SplitOwnerRepo(nameWithOwner string) (name string, owner string, err error)
 
 	return parts[0], parts[1], nil
 }
+
+const DefaultRESTURL = "https://api.github.com"
+
+// DefaultProfileViewsURL is the komarev.com counter badge the profile views are read from.
+const DefaultProfileViewsURL = "https://komarev.com/ghpvc/"
+
+// NewEndpoints builds the REST and GraphQL endpoints for a GitHub instance, using the default profile views counter.
+// An empty REST URL falls back to github.com. An empty GraphQL URL is derived from the REST URL,
+// which maps "https://api.github.com" to ".../graphql" and a GitHub Enterprise Server "https://host/api/v3" to "https://host/api/graphql".
+func NewEndpoints(restURL string, graphqlURL string) Endpoints {
+	restURL = strings.TrimRight(strings.TrimSpace(restURL), "/")
+	graphqlURL = strings.TrimRight(strings.TrimSpace(graphqlURL), "/")
+
+	if restURL == "" {
+		restURL = DefaultRESTURL
+	}
+
+	if graphqlURL == "" {
+		if base, found := strings.CutSuffix(restURL, "/api/v3"); found {
+			graphqlURL = base + "/api/graphql"
+		} else {
+			graphqlURL = restURL + "/graphql"
+		}
+	}
+
+	return Endpoints{REST: restURL, GraphQL: graphqlURL, ProfileViews: DefaultProfileViewsURL}
+}
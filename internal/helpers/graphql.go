@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 
 	"github.com/hasura/go-graphql-client"
@@ -23,6 +22,7 @@ func StringPtrOrNil(v graphql.String) *string {
 
 // RunQuery sends a graphql query that is defined by a struct with the hasura graphql client.
 // Variables are passed into the query by the client.
+// Failed requests are retried by the client's RetryTransport.
 // It returns an error if present and directly puts the result in to the query object sent as a parameter.
 func RunQuery(client *graphql.Client, query any, variables map[string]any) error {
 	if variables == nil {
@@ -31,23 +31,22 @@ func RunQuery(client *graphql.Client, query any, variables map[string]any) error
 
 	err := client.Query(context.Background(), query, variables)
 	if err != nil {
-		log.Fatalf("Failed GraphQL query: %s\n%s", err, variables)
-		return err
+		return fmt.Errorf("failed GraphQL query: %w (variables: %v)", err, variables)
 	}
 
 	return nil
 }
 
 // RunRawQuery sends a raw graphql query from a query string using a base http client.
-// It encodes the query string into json and sends it to the Github graphql endpoint.
+// It encodes the query string into json and sends it to the given Github graphql endpoint.
 // It returns the response, decoded from json into a map of string value pairs.
-func RunRawQuery(httpClient *http.Client, query string) (map[string]any, error) {
+func RunRawQuery(httpClient *http.Client, endpoint string, query string) (map[string]any, error) {
 	// Encode query string into json
 	payload := map[string]string{"query": query}
 	body, _ := json.Marshal(payload)
 
 	// Build query from json payload
-	req, err := http.NewRequest("POST", "https://api.github.com/graphql", bytes.NewBuffer(body))
+	req, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(body))
 	if err != nil {
 		return nil, err
 	}
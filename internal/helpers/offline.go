@@ -0,0 +1,13 @@
+package helpers
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// OfflineTransport fails every request, for snapshots that must be served from saved data only.
+type OfflineTransport struct{}
+
+func (OfflineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("network access is disabled, cannot request %s", req.URL)
+}
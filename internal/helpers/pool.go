@@ -0,0 +1,35 @@
+package helpers
+
+import "sync"
+
+// RunPool calls fn for every item using at most workers goroutines at a time.
+// Each call writes only to its own slot of the result slice, so fn does not need to synchronise with other calls.
+// It returns the results in the same order as items.
+func RunPool[T any, R any](workers int, items []T, fn func(T) R) []R {
+	results := make([]R, len(items))
+	if workers < 1 {
+		workers = 1
+	}
+	workers = min(workers, len(items))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = fn(items[i])
+			}
+		}()
+	}
+
+	for i := range items {
+		jobs <- i
+	}
+	close(jobs)
+
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,43 @@
+package helpers
+
+import (
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunPool(t *testing.T) {
+	items := []int{5, 4, 3, 2, 1, 0}
+
+	for _, workers := range []int{-1, 0, 1, 3, 100} {
+		var running, peak atomic.Int32
+		results := RunPool(workers, items, func(n int) int {
+			current := running.Add(1)
+			for {
+				old := peak.Load()
+				if current <= old || peak.CompareAndSwap(old, current) {
+					break
+				}
+			}
+			// Later items finish first, so the results only keep their order if the pool puts them back in place
+			time.Sleep(time.Duration(n) * time.Millisecond)
+			running.Add(-1)
+			return n * 10
+		})
+
+		if want := []int{50, 40, 30, 20, 10, 0}; !slices.Equal(results, want) {
+			t.Errorf("workers %d: results = %v, want %v", workers, results, want)
+		}
+		// Fewer than one worker runs the items one at a time, more workers than items start one per item
+		if limit := min(max(workers, 1), len(items)); int(peak.Load()) > limit {
+			t.Errorf("workers %d: %d calls ran at once, want at most %d", workers, peak.Load(), limit)
+		}
+	}
+}
+
+func TestRunPoolEmpty(t *testing.T) {
+	if results := RunPool(4, []string{}, func(s string) int { return len(s) }); len(results) != 0 {
+		t.Errorf("results = %v, want none", results)
+	}
+}
@@ -0,0 +1,259 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRateLimitRetries is the number of times a request rejected by a rate limit is resent after waiting.
+const maxRateLimitRetries = 3
+
+// defaultSecondaryWait is used when GitHub reports a secondary rate limit without a Retry-After header.
+const defaultSecondaryWait = time.Minute
+
+// RateLimiter tracks the remaining GitHub API budget of every rate limit resource (core, graphql, search, ...).
+// It is shared by every request of a run, so requests pause once a budget is exhausted instead of failing.
+type RateLimiter struct {
+	mu             sync.Mutex
+	buckets        map[string]*rateBucket
+	secondaryUntil time.Time
+}
+
+type rateBucket struct {
+	limit     int
+	remaining int
+	used      int
+	reset     time.Time
+	cost      int
+	requests  int
+}
+
+// NewRateLimiter creates an empty rate limiter. Budgets are learnt from the X-RateLimit-* headers of each response.
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		buckets: make(map[string]*rateBucket),
+	}
+}
+
+// RateLimitTransport pauses requests while their rate limit is exhausted and resends requests rejected by a rate limit.
+type RateLimitTransport struct {
+	Limiter   *RateLimiter
+	Transport http.RoundTripper
+}
+
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource := guessResource(req)
+
+	// The caller's request must not be modified, so every resend is a clone with a fresh body
+	attemptReq := req
+	for attempt := 0; ; attempt++ {
+		t.Limiter.Wait(resource)
+
+		resp, err := t.Transport.RoundTrip(attemptReq)
+		if err != nil {
+			return nil, err
+		}
+
+		// The pause before resending is enforced by Wait on the next attempt
+		if !t.Limiter.Update(resp) || attempt >= maxRateLimitRetries {
+			return resp, nil
+		}
+
+		// The request has to be sent again, which is only possible if its body can be recreated
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		log.Printf("Rate limited on %s, retrying", req.URL.Path)
+
+		attemptReq = req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq.Body = body
+		}
+	}
+}
+
+// guessResource maps a request to the rate limit resource it is expected to count against.
+// The resource reported by GitHub in the response takes precedence once it is known.
+func guessResource(req *http.Request) string {
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/graphql"):
+		return "graphql"
+	case strings.Contains(req.URL.Path, "/search/"):
+		return "search"
+	default:
+		return "core"
+	}
+}
+
+// Wait blocks until a request against resource may be sent.
+// It pauses until the reset time if the budget of the resource is exhausted, or until a secondary rate limit has passed.
+func (r *RateLimiter) Wait(resource string) {
+	for {
+		r.mu.Lock()
+		now := time.Now()
+		var wait time.Duration
+		reason := fmt.Sprintf("GitHub %s rate limit exhausted", resource)
+
+		if r.secondaryUntil.After(now) {
+			wait = r.secondaryUntil.Sub(now)
+			reason = "GitHub secondary rate limit hit"
+		} else if bucket, ok := r.buckets[resource]; ok && bucket.remaining <= 0 && bucket.reset.After(now) {
+			wait = bucket.reset.Sub(now) + time.Second
+		} else if ok {
+			// Reserve one request so concurrent workers do not all spend the last remaining request
+			bucket.remaining--
+		}
+		r.mu.Unlock()
+
+		if wait <= 0 {
+			return
+		}
+
+		log.Printf("%s, pausing for %s", reason, wait.Round(time.Second))
+		time.Sleep(wait)
+	}
+}
+
+// Update records the rate limit headers of a response.
+// It returns true if the response was rejected by a primary or secondary rate limit and should be retried.
+func (r *RateLimiter) Update(resp *http.Response) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	header := resp.Header
+
+	resource := header.Get("X-RateLimit-Resource")
+	if resource == "" && header.Get("X-RateLimit-Remaining") != "" {
+		resource = guessResource(resp.Request)
+	}
+
+	var bucket *rateBucket
+	if resource != "" {
+		bucket = r.record(resource, header)
+	}
+
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+
+	// Secondary rate limits are signalled by a Retry-After header or only in the error message
+	if retryAfter := header.Get("Retry-After"); retryAfter != "" {
+		seconds, err := strconv.Atoi(retryAfter)
+		wait := defaultSecondaryWait
+		if err == nil {
+			wait = time.Duration(seconds) * time.Second
+		}
+		r.secondaryUntil = now.Add(wait)
+		return true
+	}
+
+	if bucket != nil && bucket.remaining <= 0 && bucket.reset.After(now) {
+		return true
+	}
+
+	if isSecondaryRateLimit(resp) {
+		r.secondaryUntil = now.Add(defaultSecondaryWait)
+		return true
+	}
+
+	return false
+}
+
+// record updates the budget of a resource from the X-RateLimit-* headers and adds the cost of the request to the run's total.
+func (r *RateLimiter) record(resource string, header http.Header) *rateBucket {
+	bucket, ok := r.buckets[resource]
+	if !ok {
+		bucket = &rateBucket{}
+		r.buckets[resource] = bucket
+	}
+	bucket.requests++
+
+	limit, errLimit := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	remaining, errRemaining := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	used, errUsed := strconv.Atoi(header.Get("X-RateLimit-Used"))
+	reset, errReset := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if errLimit != nil || errRemaining != nil || errReset != nil {
+		return bucket
+	}
+	if errUsed != nil {
+		used = limit - remaining
+	}
+
+	resetAt := time.Unix(reset, 0)
+
+	// Within the same window the cost is the growth of the used budget, a new window starts counting from zero.
+	// The first response of a run cannot be compared against anything, so it is counted as a single point.
+	switch {
+	case !ok:
+		bucket.cost++
+	case used >= bucket.used:
+		bucket.cost += used - bucket.used
+	default:
+		bucket.cost += used
+	}
+
+	bucket.limit = limit
+	bucket.remaining = remaining
+	bucket.used = used
+	bucket.reset = resetAt
+	return bucket
+}
+
+// isSecondaryRateLimit checks the error message of a rejected response for a secondary rate limit.
+// The body is restored so the caller can still read it.
+func isSecondaryRateLimit(resp *http.Response) bool {
+	if resp.Body == nil {
+		return false
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+
+	return strings.Contains(strings.ToLower(string(body)), "secondary rate limit")
+}
+
+// Summary describes the total cost and the remaining budget of every resource used during the run.
+func (r *RateLimiter) Summary() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.buckets) == 0 {
+		return "no rate limited requests were made"
+	}
+
+	resources := make([]string, 0, len(r.buckets))
+	for resource := range r.buckets {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+
+	parts := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		bucket := r.buckets[resource]
+		parts = append(parts, fmt.Sprintf("%s: %d requests costing %d points (%d/%d remaining, resets %s)",
+			resource, bucket.requests, bucket.cost, bucket.remaining, bucket.limit, bucket.reset.Format(time.TimeOnly)))
+	}
+
+	return strings.Join(parts, ", ")
+}
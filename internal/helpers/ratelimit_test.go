@@ -0,0 +1,119 @@
+package helpers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rateLimitResponse builds a response to req carrying the X-RateLimit-* headers of a resource.
+func rateLimitResponse(req *http.Request, status int, used int, remaining int, reset time.Time) *http.Response {
+	header := http.Header{}
+	header.Set("X-RateLimit-Resource", "core")
+	header.Set("X-RateLimit-Limit", strconv.Itoa(used+remaining))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	header.Set("X-RateLimit-Used", strconv.Itoa(used))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader("")), Request: req}
+}
+
+func TestRateLimiterUpdate(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "https://api.github.com/repos/octocat/hello-world", nil)
+	reset := time.Now().Add(time.Hour)
+	limiter := NewRateLimiter()
+
+	// The first response counts as one point, later ones by the growth of the used budget
+	if limiter.Update(rateLimitResponse(req, http.StatusOK, 10, 4990, reset)) {
+		t.Error("a successful response was reported as rate limited")
+	}
+	limiter.Update(rateLimitResponse(req, http.StatusOK, 13, 4987, reset))
+	if bucket := limiter.buckets["core"]; bucket.cost != 4 || bucket.requests != 2 || bucket.remaining != 4987 {
+		t.Errorf("bucket = %+v, want 2 requests costing 4 with 4987 remaining", bucket)
+	}
+
+	// An exhausted budget is retried once it resets
+	if !limiter.Update(rateLimitResponse(req, http.StatusForbidden, 5000, 0, reset)) {
+		t.Error("a response with no remaining budget was not reported as rate limited")
+	}
+
+	// Secondary rate limits are only named in the message, and the body stays readable
+	resp := rateLimitResponse(req, http.StatusForbidden, 20, 4980, reset)
+	resp.Body = io.NopCloser(strings.NewReader(`{"message": "You have exceeded a secondary rate limit"}`))
+	if !limiter.Update(resp) || !limiter.secondaryUntil.After(time.Now()) {
+		t.Error("a secondary rate limit was not recorded")
+	}
+	if body, _ := io.ReadAll(resp.Body); !strings.Contains(string(body), "secondary") {
+		t.Errorf("body = %q, want it restored", body)
+	}
+
+	// Other errors are not retried
+	if limiter.Update(rateLimitResponse(req, http.StatusNotFound, 21, 4979, reset)) {
+		t.Error("a 404 was reported as rate limited")
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	limiter := NewRateLimiter()
+
+	// Unknown resources are never paused
+	limiter.Wait("graphql")
+
+	// Every request reserves one point of the remaining budget
+	limiter.buckets["core"] = &rateBucket{remaining: 2, reset: time.Now().Add(time.Hour)}
+	limiter.Wait("core")
+	limiter.Wait("core")
+	if remaining := limiter.buckets["core"].remaining; remaining != 0 {
+		t.Errorf("remaining = %d, want 0", remaining)
+	}
+
+	// A budget whose window already reset is not waited for
+	limiter.buckets["core"].reset = time.Now().Add(-time.Minute)
+	limiter.Wait("core")
+
+	// A secondary rate limit pauses every resource until it passes
+	limiter.secondaryUntil = time.Now().Add(50 * time.Millisecond)
+	start := time.Now()
+	limiter.Wait("graphql")
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("waited %s, want at least 50ms", elapsed)
+	}
+}
+
+func TestRateLimitTransportResends(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/graphql", strings.NewReader(`{"query": "{ viewer { login } }"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := req.Body
+
+	transport := &RateLimitTransport{Limiter: NewRateLimiter(), Transport: http.DefaultTransport}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || len(bodies) != 2 || bodies[1] != bodies[0] {
+		t.Errorf("status %d after bodies %q, want the same body resent once", resp.StatusCode, bodies)
+	}
+	if req.Body != body {
+		t.Error("the caller's request body was replaced")
+	}
+}
@@ -1,70 +1,64 @@
 package helpers
 
 import (
+	"encoding/base64"
 	"fmt"
 	"io"
-	"log"
+	"mime"
 	"net/http"
 	"strings"
-	"time"
 )
 
-func RunRestQuery(client *http.Client, path string, queryParams map[string]string) ([]byte, error) {
+func RunRestQuery(client *http.Client, baseURL string, path string, queryParams map[string]string) ([]byte, error) {
 	// Make a request to the REST API
 	// :param client: HTTP client
+	// :param baseURL: REST API base URL (github.com or GitHub Enterprise Server)
 	// :param path: API path to query
 	// :param params: Query parameters to be passed to the API
 	// :return: deserialized REST JSON output
 
-	baseURL := "https://api.github.com"
 	fullURL := fmt.Sprintf("%s/%s", strings.TrimRight(baseURL, "/"), strings.TrimLeft(path, "/"))
 
-	// Attempt query 60 times until the response is ready (not a 202 response) or too many responses were received
-	for range 60 {
-		// Raw http request -> needs to be recreated on every loop
-		rawRequest, err := http.NewRequest("GET", fullURL, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to build request: %w", err)
-		}
-
-		// Setup request with headers, parameters and URI encoding
-		rawRequest.Header.Set("Accept", "application/vnd.github+json")
-		q := rawRequest.URL.Query()
-		for key, value := range queryParams {
-			q.Add(key, value)
-		}
-		rawRequest.URL.RawQuery = q.Encode()
-
-		resp, err := client.Do(rawRequest)
-		// If error, wait 2 seconds and then resend the request
-		if err != nil {
-			log.Printf("HTTP request failed: %v", err)
-			time.Sleep(2 * time.Second)
-			continue
-		}
-
-		defer resp.Body.Close()
-
-		// If 202, wait 2 seconds and then resend the request
-		if resp.StatusCode == http.StatusAccepted {
-			log.Printf("GitHub returned 202 for %s. Waiting and retrying...", path)
-			time.Sleep(2 * time.Second)
-			continue
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return nil, fmt.Errorf("unexpected status %d from %s: %s", resp.StatusCode, path, body)
-		}
-
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read response body: %w", err)
-		}
-		return body, nil
-	}
-
-	return nil, fmt.Errorf("too many 202 responses from GitHub for %s", path)
+	rawRequest, err := http.NewRequest("GET", fullURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	// Setup request with headers, parameters and URI encoding
+	rawRequest.Header.Set("Accept", "application/vnd.github+json")
+	q := rawRequest.URL.Query()
+	for key, value := range queryParams {
+		q.Add(key, value)
+	}
+	rawRequest.URL.RawQuery = q.Encode()
+
+	// Transport errors, retryable statuses and 202 responses are retried by the client's RetryTransport
+	resp, err := client.Do(rawRequest)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", path, err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusAccepted {
+		return nil, fmt.Errorf("GitHub is still computing %s, too many 202 responses", path)
+	}
+
+	// Some endpoints, e.g. the contributors of an empty repo, answer without content
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status %d from %s: %s", resp.StatusCode, path, body)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return body, nil
 }
 
 func RunSVGRestQuery(client *http.Client, path string, queryParams map[string]string) (string, error) {
@@ -110,6 +104,31 @@ func RunSVGRestQuery(client *http.Client, path string, queryParams map[string]st
 	return string(body), nil
 }
 
+// FetchDataURI downloads an image and encodes it as a data URI, so it can be inlined into a card.
+// Cards are displayed as images, which are not allowed to load anything from other URLs.
+func FetchDataURI(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to get response: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "image/") {
+		return "", fmt.Errorf("unexpected content type: %s", resp.Header.Get("Content-Type"))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(body), nil
+}
+
 func (t *TransportWithToken) RoundTrip(req *http.Request) (*http.Response, error) {
 	req.Header.Set("Authorization", "Bearer "+t.Token)
 	req.Header.Set("Accept", "application/vnd.github+json")
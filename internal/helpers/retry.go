@@ -0,0 +1,110 @@
+package helpers
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy decides how often and how long to wait before a failed GitHub API call is sent again.
+// Delays grow exponentially from BaseDelay up to MaxDelay, with jitter so concurrent workers do not retry in lockstep.
+type RetryPolicy struct {
+	MaxAttempts     int           // Total attempts including the first one
+	BaseDelay       time.Duration // Delay before the first retry, doubled for every further retry
+	MaxDelay        time.Duration // Upper bound of a single delay
+	RetryableStatus []int         // Status codes that are worth retrying, transport errors are always retried
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:     5,
+		BaseDelay:       time.Second,
+		MaxDelay:        30 * time.Second,
+		RetryableStatus: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	}
+}
+
+// Backoff returns the delay before retry number attempt (starting at 0).
+// Half of the delay is fixed and the other half is random.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.MaxDelay
+	if attempt < 32 {
+		delay = min(p.BaseDelay<<attempt, p.MaxDelay)
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+// Retryable reports whether a response with the given status code should be retried.
+func (p RetryPolicy) Retryable(status int) bool {
+	for _, s := range p.RetryableStatus {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryTransport resends requests that failed with a transport error or a retryable status code according to Policy.
+// GET requests answered with 202 Accepted are retried as well, since GitHub uses it for statistics that are still being computed.
+// It sits below TransportWithToken, so REST, typed graphql and raw graphql calls are all retried the same way.
+type RetryTransport struct {
+	Policy    RetryPolicy
+	Transport http.RoundTripper
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Buffer the body once so every attempt can send it again, the caller's body is closed as the RoundTripper contract requires
+	getBody := req.GetBody
+	if req.Body != nil && getBody == nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		getBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	} else if req.Body != nil {
+		req.Body.Close()
+	}
+
+	attempts := max(t.Policy.MaxAttempts, 1)
+	for attempt := 0; ; attempt++ {
+		// The caller's request must not be modified, so every attempt sends a clone with a fresh body
+		attemptReq := req.Clone(req.Context())
+		if getBody != nil {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq.Body = body
+			attemptReq.GetBody = getBody
+		}
+
+		resp, err := t.Transport.RoundTrip(attemptReq)
+
+		retry := err != nil || t.Policy.Retryable(resp.StatusCode) ||
+			(req.Method == http.MethodGet && resp.StatusCode == http.StatusAccepted)
+		if !retry || attempt+1 >= attempts {
+			return resp, err
+		}
+
+		if err != nil {
+			log.Printf("Request to %s failed: %v (attempt %d/%d)", req.URL.Path, err, attempt+1, attempts)
+		} else {
+			log.Printf("Request to %s returned %d (attempt %d/%d)", req.URL.Path, resp.StatusCode, attempt+1, attempts)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		time.Sleep(t.Policy.Backoff(attempt))
+	}
+}
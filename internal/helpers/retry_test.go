@@ -0,0 +1,126 @@
+package helpers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	policy := DefaultRetryPolicy()
+
+	// Each delay is between half and all of BaseDelay doubled per attempt, capped at MaxDelay
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second} {
+		for range 20 {
+			if delay := policy.Backoff(attempt); delay < want/2 || delay > want {
+				t.Errorf("attempt %d: delay %s, want between %s and %s", attempt, delay, want/2, want)
+			}
+		}
+	}
+	if delay := policy.Backoff(100); delay > policy.MaxDelay {
+		t.Errorf("attempt 100: delay %s exceeds MaxDelay", delay)
+	}
+
+	// Statistics still being computed used to be polled for up to 60 x 2s. With the default policy a 202 is
+	// only waited for over MaxAttempts-1 backoffs, i.e. at most 1+2+4+8 = 15s before the metric degrades.
+	var budget time.Duration
+	for attempt := range policy.MaxAttempts - 1 {
+		budget += policy.Backoff(attempt)
+	}
+	if budget < 7500*time.Millisecond || budget > 15*time.Second {
+		t.Errorf("202 budget = %s, want between 7.5s and 15s", budget)
+	}
+
+	if delay := (RetryPolicy{}).Backoff(3); delay != 0 {
+		t.Errorf("zero policy delay = %s, want 0", delay)
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		method      string
+		status      int
+		maxAttempts int
+		want        int // Requests sent
+	}{
+		{"retryable status", http.MethodPost, http.StatusBadGateway, 3, 3},
+		{"service unavailable", http.MethodGet, http.StatusServiceUnavailable, 3, 3},
+		{"gateway timeout", http.MethodGet, http.StatusGatewayTimeout, 3, 3},
+		{"accepted get", http.MethodGet, http.StatusAccepted, 4, 4},
+		{"accepted post", http.MethodPost, http.StatusAccepted, 4, 1},
+		{"not found", http.MethodGet, http.StatusNotFound, 3, 1},
+		{"single attempt", http.MethodGet, http.StatusBadGateway, 1, 1},
+		{"no attempts", http.MethodGet, http.StatusBadGateway, 0, 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var bodies []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(body))
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			policy := DefaultRetryPolicy()
+			policy.MaxAttempts = tt.maxAttempts
+			policy.BaseDelay = 0
+			transport := &RetryTransport{Policy: policy, Transport: http.DefaultTransport}
+
+			var body io.Reader
+			if tt.method == http.MethodPost {
+				body = io.NopCloser(strings.NewReader("payload")) // Without GetBody, so the transport has to buffer it
+			}
+			req, err := http.NewRequest(tt.method, server.URL, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			original := req.Body
+
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.status || len(bodies) != tt.want {
+				t.Errorf("status %d after %d requests, want %d after %d", resp.StatusCode, len(bodies), tt.status, tt.want)
+			}
+			for _, sent := range bodies {
+				if tt.method == http.MethodPost && sent != "payload" {
+					t.Errorf("sent body %q, want payload on every attempt", sent)
+				}
+			}
+			if req.Body != original || req.GetBody != nil {
+				t.Error("the caller's request was modified")
+			}
+		})
+	}
+}
+
+func TestRetryTransportSucceedsAfterFailure(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	transport := &RetryTransport{Policy: RetryPolicy{MaxAttempts: 3, RetryableStatus: DefaultRetryPolicy().RetryableStatus}, Transport: http.DefaultTransport}
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || requests != 2 {
+		t.Errorf("status %d after %d requests, want 200 after 2", resp.StatusCode, requests)
+	}
+}
@@ -13,3 +13,11 @@ type TransportWithToken struct {
 	Token     string
 	Transport http.RoundTripper
 }
+
+// Endpoints holds the base URLs used to reach the GitHub REST and GraphQL APIs, and the profile views counter.
+// It allows the snapshot to be generated against GitHub Enterprise Server as well as github.com, or against a fake server in tests.
+type Endpoints struct {
+	REST         string
+	GraphQL      string
+	ProfileViews string
+}
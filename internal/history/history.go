@@ -0,0 +1,133 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"snapshot/internal/snapshot"
+)
+
+// DateLayout is the layout of Entry.Date. Entries are keyed by UTC calendar day.
+const DateLayout = "2006-01-02"
+
+// Entry is a single day in the history store, stored as one JSON object per line.
+type Entry struct {
+	Date        string                `json:"date"`
+	RecordedAt  time.Time             `json:"recordedAt"`
+	Metrics     snapshot.ExportTotals `json:"metrics"`
+	Unavailable []string              `json:"unavailable,omitempty"` // Metrics that failed to compute in this run
+}
+
+// NewEntry builds the history entry for an exported snapshot, keyed by the day it was generated.
+func NewEntry(export snapshot.Export) Entry {
+	return Entry{
+		Date:       export.GeneratedAt.UTC().Format(DateLayout),
+		RecordedAt: export.GeneratedAt.UTC(),
+		Metrics:    export.Totals,
+
+		Unavailable: export.Unavailable,
+	}
+}
+
+// Load reads every entry from the JSON Lines file at path, sorted by date.
+// A missing file is treated as an empty history.
+func Load(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid history entry on line %d of %s: %w", line, path, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date < entries[j].Date
+	})
+	return entries, nil
+}
+
+// Append adds entry to the history file at path.
+// An existing entry for the same date is replaced so repeated runs on one day only keep the latest result.
+func Append(path string, entry Entry) error {
+	entries, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i := range entries {
+		if entries[i].Date == entry.Date {
+			entries[i] = entry
+			replaced = true
+		}
+	}
+	if !replaced {
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date < entries[j].Date
+	})
+
+	return write(path, entries)
+}
+
+// write replaces the history file with entries through a temporary file so an interrupted run cannot truncate it.
+func write(path string, entries []Entry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".history-*.jsonl")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
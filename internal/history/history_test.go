@@ -0,0 +1,63 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"snapshot/internal/snapshot"
+)
+
+func entry(date string, stars int) Entry {
+	return Entry{Date: date, Metrics: snapshot.ExportTotals{Stars: stars}}
+}
+
+func TestLoadMissing(t *testing.T) {
+	entries, err := Load(filepath.Join(t.TempDir(), "history.jsonl"))
+	if err != nil || entries != nil {
+		t.Errorf("entries, err = %v, %v, want an empty history", entries, err)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "history.jsonl")
+
+	// Entries are kept sorted by date, and a second run on the same day replaces the first
+	for _, e := range []Entry{entry("2025-01-02", 2), entry("2025-01-01", 1), entry("2025-01-02", 3)} {
+		if err := Append(path, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Date != "2025-01-01" || entries[1].Date != "2025-01-02" || entries[1].Metrics.Stars != 3 {
+		t.Errorf("entries = %+v, want 2025-01-01 and the latest 2025-01-02", entries)
+	}
+
+	// The file is replaced through a temporary file, which is cleaned up
+	files, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("files = %v, want only history.jsonl", files)
+	}
+}
+
+func TestAppendKeepsInvalidHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	if err := os.WriteFile(path, []byte("{\"date\":\"2025-01-01\"}\nnot json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A history that cannot be read is left untouched instead of being overwritten
+	if err := Append(path, entry("2025-01-02", 1)); err == nil {
+		t.Fatal("expected an error for the invalid line")
+	}
+	if dat, _ := os.ReadFile(path); string(dat) != "{\"date\":\"2025-01-01\"}\nnot json\n" {
+		t.Errorf("history was rewritten to %q", dat)
+	}
+}
@@ -0,0 +1,99 @@
+package history
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Delta is the change of every metric between the current run and the latest entry at least Days old.
+type Delta struct {
+	Days          int
+	Label         string // Human readable window, e.g. "this week"
+	Since         string // Date of the entry the current run was compared against
+	Stars         int64
+	Forks         int64
+	Contributions int64
+	LinesChanged  int64
+	Repos         int64
+	Views         int64
+	ProfileViews  int64
+}
+
+// WindowLabel describes a comparison window of the given number of days.
+func WindowLabel(days int) string {
+	switch days {
+	case 1:
+		return "today"
+	case 7:
+		return "this week"
+	case 30, 31:
+		return "this month"
+	case 365:
+		return "this year"
+	default:
+		return fmt.Sprintf("in %d days", days)
+	}
+}
+
+// Compare computes the change between current and the most recent entry dated at least days before it.
+// The entry must not be more than another window older, so the change still matches its label after a gap in the history.
+// It returns false if the history does not reach back far enough or has no entry within that range.
+func Compare(entries []Entry, current Entry, days int) (Delta, bool) {
+	today, err := time.Parse(DateLayout, current.Date)
+	if err != nil {
+		return Delta{}, false
+	}
+	cutoff := today.AddDate(0, 0, -days).Format(DateLayout)
+	oldest := today.AddDate(0, 0, -2*days).Format(DateLayout)
+
+	// Entries are sorted by date, so the last match is the closest to the cutoff
+	var baseline *Entry
+	for i := range entries {
+		if entries[i].Date <= cutoff {
+			baseline = &entries[i]
+		}
+	}
+	if baseline == nil || baseline.Date < oldest {
+		return Delta{}, false
+	}
+
+	now, then := current.Metrics, baseline.Metrics
+
+	// A metric that failed in either run has no meaningful change and is left at zero
+	diff := func(key string, a int64, b int64) int64 {
+		if slices.Contains(current.Unavailable, key) || slices.Contains(baseline.Unavailable, key) {
+			return 0
+		}
+		return a - b
+	}
+
+	delta := Delta{
+		Days:          days,
+		Label:         WindowLabel(days),
+		Since:         baseline.Date,
+		Stars:         diff("stars", int64(now.Stars), int64(then.Stars)),
+		Forks:         diff("forks", int64(now.Forks), int64(then.Forks)),
+		Contributions: diff("contributions", int64(now.Contributions), int64(then.Contributions)),
+		LinesChanged:  diff("linesChanged", now.LinesChanged, then.LinesChanged),
+		Repos:         diff("repos", int64(now.Repos), int64(then.Repos)),
+		Views:         diff("views", int64(now.Views), int64(then.Views)),
+	}
+
+	if now.ProfileViews != nil && then.ProfileViews != nil {
+		delta.ProfileViews = diff("profileViews", int64(*now.ProfileViews), int64(*then.ProfileViews))
+	}
+
+	return delta, true
+}
+
+// Trends compares current against the history for every window, skipping windows the history cannot cover yet.
+func Trends(entries []Entry, current Entry, windows []int) []Delta {
+	var trends []Delta
+	for _, days := range windows {
+		if delta, ok := Compare(entries, current, days); ok {
+			trends = append(trends, delta)
+		}
+	}
+	return trends
+}
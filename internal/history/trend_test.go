@@ -0,0 +1,35 @@
+package history
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	entries := []Entry{entry("2025-01-01", 10), entry("2025-01-20", 20), entry("2025-01-23", 25)}
+	current := entry("2025-01-31", 40)
+
+	// The latest entry at least a week old is the baseline
+	delta, ok := Compare(entries, current, 7)
+	if !ok || delta.Since != "2025-01-23" || delta.Stars != 15 || delta.Label != "this week" {
+		t.Errorf("delta = %+v, %v, want +15 since 2025-01-23", delta, ok)
+	}
+
+	// Without an entry for the last month the history does not reach back far enough
+	if delta, ok := Compare(entries, current, 31); ok {
+		t.Errorf("delta = %+v, want none for a window the history does not cover", delta)
+	}
+}
+
+func TestCompareAfterGap(t *testing.T) {
+	// The history stopped for weeks, so its last entry is far older than the window
+	entries := []Entry{entry("2024-11-01", 10), entry("2025-01-30", 30)}
+	current := entry("2025-01-31", 40)
+
+	if delta, ok := Compare(entries, current, 7); ok {
+		t.Errorf("delta = %+v, want none instead of a change since 2024-11-01 labelled this week", delta)
+	}
+
+	// An entry up to another window older is still accepted
+	entries[0].Date = "2025-01-17"
+	if delta, ok := Compare(entries, current, 7); !ok || delta.Since != "2025-01-17" {
+		t.Errorf("delta = %+v, %v, want a change since 2025-01-17", delta, ok)
+	}
+}
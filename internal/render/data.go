@@ -0,0 +1,234 @@
+package render
+
+import (
+	"snapshot/internal/helpers"
+	"snapshot/internal/history"
+	"snapshot/internal/snapshot"
+	"time"
+)
+
+// Options controls how the snapshot is laid out for the templates.
+type Options struct {
+	Theme          string    // Colour scheme of the cards: auto, light or dark
+	HeatmapYear    int       // Calendar year shown on the heatmap, 0 for the last 52 weeks
+	TopReposMetric string    // Metric the top repositories are ranked by, one of RepoMetrics
+	TopReposCount  int       // Number of repositories shown on the top repositories card
+	Today          time.Time // Day the heatmap of the last 52 weeks ends on, the current day if zero
+}
+
+// Data is the model every card template is rendered against.
+// Templates reference its fields directly, e.g. {{ .Stars }} or {{ range .Languages }}.
+type Data struct {
+	Name                string          // Display name of the user or organization, falling back to their login
+	Organization        *Organization   // Set for organization snapshots, nil for user snapshots
+	Leaderboard         *Leaderboard    // Set for team leaderboards, nil for user and organization snapshots
+	Theme               string          // Colour scheme of the cards: auto, light or dark
+	Stars               int             // Stargazers across all counted repositories
+	Forks               int             // Forks across all counted repositories
+	Contributions       Metric          // All-time contributions
+	LinesChanged        Metric          // Lines added plus lines deleted by the user
+	Repos               int             // Number of counted repositories
+	Views               Metric          // Repository views over the past two weeks
+	PullRequests        Metric          // Pull requests opened across all years
+	MergedPullRequests  Metric          // Pull requests authored by the user that were merged
+	Issues              Metric          // Issues opened across all years
+	ClosedIssues        Metric          // Issues authored by the user that were closed
+	Reviews             Metric          // Pull request reviews given across all years
+	IncludeProfileViews bool            // Whether profile views were requested
+	ProfileViews        Metric          // Profile views, only populated when IncludeProfileViews is set
+	Languages           []Language      // Languages sorted by size, largest first
+	TopRepos            []Repo          // Best repositories ranked by TopReposMetric, best first
+	TopReposMetric      string          // Metric TopRepos is ranked by: stars, forks, lines or views
+	Heatmap             Heatmap         // Contribution calendar grid
+	CurrentStreak       Streak          // Ongoing run of days with contributions
+	LongestStreak       Streak          // Longest run of days with contributions across all years
+	ActiveDays          Metric          // Days with at least one contribution across all years
+	Trend               *history.Delta  // Change over the first configured window, nil until the history covers it
+	Trends              []history.Delta // Change over every configured window the history covers
+}
+
+// Organization is the organization of an organization snapshot as exposed to templates.
+type Organization struct {
+	Login           string
+	Description     string
+	Avatar          string        // Data URI of the avatar, empty if it could not be fetched
+	Contributors    Metric        // Number of people who committed to the counted repositories
+	TopContributors []Contributor // Everyone who committed to the counted repositories, most commits first
+}
+
+// Contributor is a person who committed to the counted repositories of an organization.
+type Contributor struct {
+	Login         string
+	Contributions int // Commits across all counted repositories
+}
+
+// Streak is a run of consecutive days with contributions. Start and End are YYYY-MM-DD dates.
+type Streak struct {
+	Length Metric
+	Start  string
+	End    string
+}
+
+// Language is a single language entry as exposed to templates.
+type Language struct {
+	Name        string
+	Colour      string
+	Size        int
+	Occurrences int
+	Percent     float64 // Share of the total language size, between 0 and 100
+}
+
+// NewData collects every metric from the snapshot into the template data model.
+func NewData(s *snapshot.Snapshot, opts Options) (Data, error) {
+	name, err := snapshot.GetName(s)
+	if err != nil {
+		return Data{}, err
+	}
+	repos, err := snapshot.GetRepos(s)
+	if err != nil {
+		return Data{}, err
+	}
+	languages, err := snapshot.GetLanguages(s)
+	if err != nil {
+		return Data{}, err
+	}
+	stars, _ := snapshot.GetStargazers(s)
+	forks, _ := snapshot.GetForks(s)
+
+	data := Data{
+		Name:                name,
+		Stars:               stars,
+		Forks:               forks,
+		Repos:               len(repos),
+		Theme:               opts.Theme,
+		IncludeProfileViews: s.IncludeProfileViews,
+		TopReposMetric:      opts.TopReposMetric,
+	}
+
+	// Every other metric degrades to a placeholder on failure, unless the error is fatal
+	metrics := []struct {
+		metric *Metric
+		get    func() (Metric, error)
+	}{
+		{&data.Contributions, func() (Metric, error) { return available(snapshot.GetContributions(s)) }},
+		{&data.LinesChanged, func() (Metric, error) { return available(snapshot.GetLinesChanged(s)) }},
+		{&data.Views, func() (Metric, error) { return available(snapshot.GetViews(s)) }},
+		{&data.ActiveDays, func() (Metric, error) { return available(snapshot.GetActiveDays(s)) }},
+	}
+	if s.IncludeProfileViews {
+		metrics = append(metrics, struct {
+			metric *Metric
+			get    func() (Metric, error)
+		}{&data.ProfileViews, func() (Metric, error) { return available(snapshot.GetProfileViews(s)) }})
+	}
+	for _, m := range metrics {
+		if *m.metric, err = m.get(); err != nil {
+			return Data{}, err
+		}
+	}
+
+	activity, err := snapshot.GetActivity(s)
+	if snapshot.IsFatal(err) {
+		return Data{}, err
+	}
+	data.PullRequests, _ = available(activity.PullRequests, err)
+	data.MergedPullRequests, _ = available(activity.MergedPullRequests, err)
+	data.Issues, _ = available(activity.Issues, err)
+	data.ClosedIssues, _ = available(activity.ClosedIssues, err)
+	data.Reviews, _ = available(activity.Reviews, err)
+
+	streaks, err := snapshot.GetStreaks(s)
+	if snapshot.IsFatal(err) {
+		return Data{}, err
+	}
+	data.CurrentStreak = newStreak(streaks.Current, err)
+	data.LongestStreak = newStreak(streaks.Longest, err)
+
+	days, err := snapshot.GetContributionCalendar(s)
+	if snapshot.IsFatal(err) {
+		return Data{}, err
+	}
+	if err == nil {
+		today := opts.Today
+		if today.IsZero() {
+			today = time.Now()
+		}
+		data.Heatmap = NewHeatmap(days, opts.HeatmapYear, today.UTC())
+	}
+
+	if snapshot.IsOrganization(s) {
+		org, err := newOrganization(s)
+		if err != nil {
+			return Data{}, err
+		}
+		data.Organization = &org
+	}
+
+	all, err := NewRepos(s)
+	if err != nil {
+		return Data{}, err
+	}
+	data.TopRepos = TopRepos(all, opts.TopReposMetric, opts.TopReposCount)
+
+	for _, entry := range helpers.SortLanguages(languages) {
+		data.Languages = append(data.Languages, Language{
+			Name:        entry.Name,
+			Colour:      entry.Data.Colour,
+			Size:        entry.Data.Size,
+			Occurrences: entry.Data.Occurrences,
+			Percent:     entry.Data.Prop,
+		})
+	}
+
+	return data, nil
+}
+
+// newOrganization collects the organization and its contributors, which degrade to a placeholder on failure.
+func newOrganization(s *snapshot.Snapshot) (Organization, error) {
+	org, err := snapshot.GetOrganization(s)
+	if err != nil {
+		return Organization{}, err
+	}
+
+	contributors, err := snapshot.GetContributors(s)
+	if snapshot.IsFatal(err) {
+		return Organization{}, err
+	}
+
+	result := Organization{
+		Login:       org.Login,
+		Description: org.Description,
+		Avatar:      org.Avatar,
+	}
+	result.Contributors, _ = available(len(contributors), err)
+	for _, contributor := range contributors {
+		result.TopContributors = append(result.TopContributors, Contributor{
+			Login:         contributor.Login,
+			Contributions: contributor.Contributions,
+		})
+	}
+	return result, nil
+}
+
+// newStreak converts a snapshot streak, marking its length unavailable if the streaks failed to compute.
+func newStreak(streak snapshot.Streak, err error) Streak {
+	if err != nil {
+		return Streak{}
+	}
+	return Streak{
+		Length: Metric{Value: int64(streak.Length), OK: true},
+		Start:  streak.Start,
+		End:    streak.End,
+	}
+}
+
+// WithTrends attaches the deltas computed from the history to the data.
+// The first delta is exposed as .Trend for cards that only show a single window.
+func (d Data) WithTrends(trends []history.Delta) Data {
+	d.Trends = trends
+	d.Trend = nil
+	if len(trends) > 0 {
+		d.Trend = &trends[0]
+	}
+	return d
+}
@@ -0,0 +1,98 @@
+package render
+
+import (
+	"snapshot/internal/snapshot"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Heatmap is a GitHub-style contribution grid with one column per week and one row per weekday.
+type Heatmap struct {
+	Year   int    // Calendar year shown, or 0 for the last 52 weeks
+	Total  Metric // Contributions made within the shown range, unavailable if the calendar failed to load
+	Weeks  []Week // Columns of the grid, oldest first
+	Months []Month
+}
+
+// Week is a column of the heatmap. Days outside the shown range are left out, so a week may hold fewer than 7 days.
+type Week struct {
+	Index int
+	Days  []HeatmapDay
+}
+
+type HeatmapDay struct {
+	Date    string
+	Weekday int // Row of the grid, 0 is Sunday
+	Count   int
+	Level   int // 0 (no contributions) to 4 (top quartile)
+}
+
+// Month is a label placed above the week in which the month starts.
+type Month struct {
+	Name string
+	Week int
+}
+
+var contributionLevels = map[string]int{
+	"NONE":            0,
+	"FIRST_QUARTILE":  1,
+	"SECOND_QUARTILE": 2,
+	"THIRD_QUARTILE":  3,
+	"FOURTH_QUARTILE": 4,
+}
+
+// NewHeatmap lays out the contribution calendar as a grid of weeks.
+// A year of 0 shows the 52 weeks leading up to today, otherwise the given calendar year is shown.
+func NewHeatmap(days []snapshot.ContributionDay, year int, today time.Time) Heatmap {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+
+	var first, last time.Time
+	if year == 0 {
+		last = today
+		first = last.AddDate(0, 0, -int(last.Weekday())-52*7)
+	} else {
+		first = time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		last = time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+		if last.After(today) {
+			last = today
+		}
+	}
+
+	counts := make(map[string]snapshot.ContributionDay, len(days))
+	for _, day := range days {
+		counts[day.Date] = day
+	}
+
+	heatmap := Heatmap{Year: year, Total: Metric{OK: true}}
+
+	// Columns always start on a Sunday, so the first column may begin before the first shown day
+	start := first.AddDate(0, 0, -int(first.Weekday()))
+	for weekStart := start; !weekStart.After(last); weekStart = weekStart.AddDate(0, 0, 7) {
+		week := Week{Index: len(heatmap.Weeks)}
+
+		for weekday := range 7 {
+			date := weekStart.AddDate(0, 0, weekday)
+			if date.Before(first) || date.After(last) {
+				continue
+			}
+
+			if date.Day() == 1 || date.Equal(first) && date.Day() <= 7 {
+				heatmap.Months = append(heatmap.Months, Month{Name: date.Format("Jan"), Week: week.Index})
+			}
+
+			day := counts[date.Format(dateLayout)]
+			heatmap.Total.Value += int64(day.Count)
+			week.Days = append(week.Days, HeatmapDay{
+				Date:    date.Format(dateLayout),
+				Weekday: weekday,
+				Count:   day.Count,
+				Level:   contributionLevels[day.Level],
+			})
+		}
+
+		heatmap.Weeks = append(heatmap.Weeks, week)
+	}
+
+	return heatmap
+}
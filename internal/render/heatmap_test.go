@@ -0,0 +1,99 @@
+package render
+
+import (
+	"testing"
+	"time"
+
+	"snapshot/internal/snapshot"
+)
+
+var heatmapToday = time.Date(2025, time.January, 31, 15, 0, 0, 0, time.UTC) // A Friday
+
+func heatmapDays() []snapshot.ContributionDay {
+	return []snapshot.ContributionDay{
+		{Date: "2023-12-31", Count: 100, Level: "FOURTH_QUARTILE"},
+		{Date: "2024-01-01", Count: 2, Level: "FIRST_QUARTILE"},
+		{Date: "2024-06-15", Count: 5, Level: "THIRD_QUARTILE"},
+		{Date: "2024-12-31", Count: 1, Level: "FIRST_QUARTILE"},
+		{Date: "2025-01-31", Count: 4, Level: "SECOND_QUARTILE"},
+	}
+}
+
+// findDay returns the cell of the heatmap for date.
+func findDay(heatmap Heatmap, date string) (HeatmapDay, bool) {
+	for _, week := range heatmap.Weeks {
+		for _, day := range week.Days {
+			if day.Date == date {
+				return day, true
+			}
+		}
+	}
+	return HeatmapDay{}, false
+}
+
+func TestNewHeatmapYear(t *testing.T) {
+	heatmap := NewHeatmap(heatmapDays(), 2024, heatmapToday)
+
+	// Only days of 2024 are shown and counted
+	if heatmap.Year != 2024 || heatmap.Total != metric(8) {
+		t.Errorf("year, total = %d, %v, want 2024, 8", heatmap.Year, heatmap.Total)
+	}
+	if len(heatmap.Weeks) != 53 {
+		t.Fatalf("weeks = %d, want 53", len(heatmap.Weeks))
+	}
+
+	// Weeks start on Sunday, so January 1st (a Monday) opens a partial first column
+	first := heatmap.Weeks[0].Days
+	if len(first) != 6 || first[0].Date != "2024-01-01" || first[0].Weekday != 1 {
+		t.Errorf("first week = %+v, want 6 days starting on Monday 2024-01-01", first)
+	}
+	last := heatmap.Weeks[52].Days
+	if len(last) != 3 || last[2].Date != "2024-12-31" || last[2].Weekday != 2 {
+		t.Errorf("last week = %+v, want Sunday 2024-12-29 to Tuesday 2024-12-31", last)
+	}
+	if heatmap.Months[0] != (Month{Name: "Jan", Week: 0}) || len(heatmap.Months) != 12 {
+		t.Errorf("months = %+v, want 12 labels starting with Jan", heatmap.Months)
+	}
+}
+
+func TestNewHeatmapLastWeeks(t *testing.T) {
+	heatmap := NewHeatmap(heatmapDays(), 0, heatmapToday)
+
+	// 52 full weeks before the current one, which ends today
+	if len(heatmap.Weeks) != 53 {
+		t.Fatalf("weeks = %d, want 53", len(heatmap.Weeks))
+	}
+	if first := heatmap.Weeks[0].Days; len(first) != 7 || first[0].Date != "2024-01-28" || first[0].Weekday != 0 {
+		t.Errorf("first week = %+v, want a full week starting on Sunday 2024-01-28", first)
+	}
+	if last := heatmap.Weeks[52].Days; len(last) != 6 || last[5].Date != "2025-01-31" {
+		t.Errorf("last week = %+v, want Sunday to today", last)
+	}
+	if heatmap.Total != metric(10) {
+		t.Errorf("total = %v, want 10", heatmap.Total)
+	}
+	for i, week := range heatmap.Weeks {
+		if week.Index != i {
+			t.Errorf("week %d has index %d", i, week.Index)
+		}
+	}
+}
+
+func TestNewHeatmapMissingDays(t *testing.T) {
+	heatmap := NewHeatmap(heatmapDays(), 2024, heatmapToday)
+
+	// Days missing from the calendar are shown empty rather than left out
+	day, ok := findDay(heatmap, "2024-03-10")
+	if !ok || day.Count != 0 || day.Level != 0 || day.Weekday != 0 {
+		t.Errorf("missing day = %+v, %v, want an empty Sunday", day, ok)
+	}
+	if day, ok := findDay(heatmap, "2024-06-15"); !ok || day.Count != 5 || day.Level != 3 {
+		t.Errorf("2024-06-15 = %+v, %v, want 5 contributions at level 3", day, ok)
+	}
+
+	// A year that is not over yet stops at today
+	current := NewHeatmap(heatmapDays(), 2025, heatmapToday)
+	if _, ok := findDay(current, "2025-02-01"); ok || current.Total != metric(4) {
+		t.Errorf("current year shows days after today or total %v, want 4", current.Total)
+	}
+}
@@ -0,0 +1,177 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"snapshot/internal/snapshot"
+)
+
+// RecentDays is the window of the recentContributions metric, ending on the day the leaderboard is generated.
+const RecentDays = 30
+
+// LeaderboardMetrics are the metrics team members can be ranked by on the leaderboard card.
+var LeaderboardMetrics = []string{"recentContributions", "mergedPullRequests", "linesChanged"}
+
+// leaderboardLabels are the column headers of the leaderboard metrics.
+var leaderboardLabels = map[string]string{
+	"recentContributions": fmt.Sprintf("Last %d days", RecentDays),
+	"mergedPullRequests":  "PRs merged",
+	"linesChanged":        "Lines changed",
+}
+
+// Leaderboard is a team ranked by one or more metrics, as exposed to templates.
+type Leaderboard struct {
+	Metrics []string // Metrics the members are ranked by, later ones breaking ties of the earlier ones
+	Labels  []string // Column header of every metric, in the order of Metrics
+	Members []Member // Ranked best first
+}
+
+// Member is a single user of a team leaderboard.
+type Member struct {
+	Rank                int // Position on the leaderboard, shared by members tied on every metric
+	Login               string
+	Name                string
+	Avatar              string // Data URI of the avatar, empty if it could not be fetched
+	RecentContributions Metric // Contributions over the last RecentDays days
+	MergedPullRequests  Metric // Pull requests authored by the user that were merged
+	LinesChanged        Metric // Lines added plus lines deleted by the user
+	Values              []Metric
+}
+
+// ValidateLeaderboardMetrics returns an error if a team cannot be ranked by the given metrics.
+func ValidateLeaderboardMetrics(metrics []string) error {
+	if len(metrics) == 0 {
+		return fmt.Errorf("at least one metric is needed, expected some of %s", strings.Join(LeaderboardMetrics, ", "))
+	}
+	for i, metric := range metrics {
+		if !slices.Contains(LeaderboardMetrics, metric) {
+			return fmt.Errorf("unknown leaderboard metric %q, expected one of %s", metric, strings.Join(LeaderboardMetrics, ", "))
+		}
+		if slices.Contains(metrics[:i], metric) {
+			return fmt.Errorf("leaderboard metric %q is listed twice", metric)
+		}
+	}
+	return nil
+}
+
+// NewMember collects the leaderboard metrics of a user snapshot.
+// Metrics that failed to compute are ranked last, only a fatal error is returned.
+func NewMember(s *snapshot.Snapshot, today time.Time) (Member, error) {
+	name, err := snapshot.GetName(s)
+	if err != nil {
+		return Member{}, err
+	}
+	avatar, err := snapshot.GetAvatar(s)
+	if err != nil {
+		return Member{}, err
+	}
+
+	member := Member{Login: snapshot.GetLogin(s), Name: name, Avatar: avatar}
+	if member.LinesChanged, err = available(snapshot.GetLinesChanged(s)); err != nil {
+		return Member{}, err
+	}
+
+	activity, err := snapshot.GetActivity(s)
+	if snapshot.IsFatal(err) {
+		return Member{}, err
+	}
+	member.MergedPullRequests, _ = available(activity.MergedPullRequests, err)
+
+	days, err := snapshot.GetContributionCalendar(s)
+	if snapshot.IsFatal(err) {
+		return Member{}, err
+	}
+	member.RecentContributions, _ = available(recentContributions(days, today), err)
+
+	return member, nil
+}
+
+// recentContributions adds up the contributions of the RecentDays days ending on today.
+func recentContributions(days []snapshot.ContributionDay, today time.Time) int {
+	last := today.UTC().Format(dateLayout)
+	first := today.UTC().AddDate(0, 0, 1-RecentDays).Format(dateLayout)
+
+	total := 0
+	for _, day := range days {
+		// YYYY-MM-DD dates sort chronologically as strings
+		if day.Date >= first && day.Date <= last {
+			total += day.Count
+		}
+	}
+	return total
+}
+
+// NewLeaderboard ranks the members by the metrics in order, then by login.
+// A metric that is unavailable for a member ranks below any value, and only the first count members are kept if count is positive.
+func NewLeaderboard(members []Member, metrics []string, count int) Leaderboard {
+	leaderboard := Leaderboard{Metrics: metrics, Members: make([]Member, 0, len(members))}
+	for _, metric := range metrics {
+		leaderboard.Labels = append(leaderboard.Labels, leaderboardLabels[metric])
+	}
+
+	for _, member := range members {
+		member.Values = nil
+		for _, metric := range metrics {
+			member.Values = append(member.Values, member.value(metric))
+		}
+		leaderboard.Members = append(leaderboard.Members, member)
+	}
+
+	ranked := leaderboard.Members
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if c := compareValues(ranked[i].Values, ranked[j].Values); c != 0 {
+			return c > 0
+		}
+		return strings.ToLower(ranked[i].Login) < strings.ToLower(ranked[j].Login)
+	})
+
+	// Members tied on every metric share a rank, the next one skips the shared places
+	for i := range ranked {
+		ranked[i].Rank = i + 1
+		if i > 0 && compareValues(ranked[i].Values, ranked[i-1].Values) == 0 {
+			ranked[i].Rank = ranked[i-1].Rank
+		}
+	}
+
+	if count > 0 && len(ranked) > count {
+		leaderboard.Members = ranked[:count]
+	}
+	return leaderboard
+}
+
+// value returns the member's value of a leaderboard metric.
+func (m Member) value(metric string) Metric {
+	switch metric {
+	case "recentContributions":
+		return m.RecentContributions
+	case "mergedPullRequests":
+		return m.MergedPullRequests
+	case "linesChanged":
+		return m.LinesChanged
+	default:
+		return Metric{}
+	}
+}
+
+// compareValues compares two rows of metric values in order, returning a positive number if a ranks above b.
+func compareValues(a []Metric, b []Metric) int {
+	for i := range a {
+		switch {
+		case a[i].OK != b[i].OK:
+			if a[i].OK {
+				return 1
+			}
+			return -1
+		case a[i].Value != b[i].Value:
+			if a[i].Value > b[i].Value {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
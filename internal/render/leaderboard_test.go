@@ -0,0 +1,69 @@
+package render
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"snapshot/internal/snapshot"
+)
+
+func TestNewLeaderboard(t *testing.T) {
+	members := []Member{
+		{Login: "octocat", RecentContributions: metric(10), MergedPullRequests: metric(5)},
+		{Login: "hubot", RecentContributions: metric(10), MergedPullRequests: metric(8)},
+		{Login: "defunkt", RecentContributions: Metric{}, MergedPullRequests: metric(50)},
+		{Login: "Monalisa", RecentContributions: metric(10), MergedPullRequests: metric(5)},
+		{Login: "ghost", RecentContributions: metric(0), MergedPullRequests: metric(0)},
+	}
+
+	// Later metrics break ties, unavailable values rank last and members tied on every metric share a rank
+	leaderboard := NewLeaderboard(members, []string{"recentContributions", "mergedPullRequests"}, 0)
+	var logins []string
+	var ranks []int
+	for _, member := range leaderboard.Members {
+		logins = append(logins, member.Login)
+		ranks = append(ranks, member.Rank)
+	}
+	if want := []string{"hubot", "Monalisa", "octocat", "ghost", "defunkt"}; !slices.Equal(logins, want) {
+		t.Errorf("logins = %v, want %v", logins, want)
+	}
+	if want := []int{1, 2, 2, 4, 5}; !slices.Equal(ranks, want) {
+		t.Errorf("ranks = %v, want %v", ranks, want)
+	}
+	if want := []string{"Last 30 days", "PRs merged"}; !slices.Equal(leaderboard.Labels, want) {
+		t.Errorf("labels = %v, want %v", leaderboard.Labels, want)
+	}
+	if values := leaderboard.Members[0].Values; len(values) != 2 || values[1] != metric(8) {
+		t.Errorf("values of the first member = %v, want [10 8]", values)
+	}
+
+	leaderboard = NewLeaderboard(members, []string{"mergedPullRequests"}, 2)
+	if len(leaderboard.Members) != 2 || leaderboard.Members[0].Login != "defunkt" {
+		t.Errorf("members = %+v, want defunkt first and only two members", leaderboard.Members)
+	}
+}
+
+func TestRecentContributions(t *testing.T) {
+	days := []snapshot.ContributionDay{
+		{Date: "2025-01-01", Count: 100}, // 31 days before today
+		{Date: "2025-01-02", Count: 1},
+		{Date: "2025-01-20", Count: 2},
+		{Date: "2025-01-31", Count: 3},
+		{Date: "2025-02-01", Count: 100},
+	}
+	if got := recentContributions(days, time.Date(2025, time.January, 31, 23, 0, 0, 0, time.UTC)); got != 6 {
+		t.Errorf("recent contributions = %d, want 6", got)
+	}
+}
+
+func TestValidateLeaderboardMetrics(t *testing.T) {
+	if err := ValidateLeaderboardMetrics(LeaderboardMetrics); err != nil {
+		t.Error(err)
+	}
+	for _, metrics := range [][]string{nil, {"stars"}, {"linesChanged", "linesChanged"}} {
+		if err := ValidateLeaderboardMetrics(metrics); err == nil {
+			t.Errorf("expected an error for %v", metrics)
+		}
+	}
+}
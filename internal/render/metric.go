@@ -0,0 +1,36 @@
+package render
+
+import (
+	"github.com/dustin/go-humanize"
+
+	"snapshot/internal/snapshot"
+)
+
+// Placeholder is shown on a card in place of a metric that could not be computed.
+const Placeholder = "—"
+
+// Metric is a number shown on a card that may have failed to compute.
+// Printing it directly or through humanize shows the formatted value, or Placeholder if it is unavailable.
+type Metric struct {
+	Value int64
+	OK    bool
+}
+
+func (m Metric) String() string {
+	if !m.OK {
+		return Placeholder
+	}
+	return humanize.Comma(m.Value)
+}
+
+// available wraps the result of a snapshot getter in a Metric.
+// A fatal error is returned so the caller can abort, any other error only marks the metric as unavailable.
+func available[T int | int64](value T, err error) (Metric, error) {
+	if err != nil {
+		if snapshot.IsFatal(err) {
+			return Metric{}, err
+		}
+		return Metric{}, nil
+	}
+	return Metric{Value: int64(value), OK: true}, nil
+}
@@ -0,0 +1,179 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// OrgCardPrefix starts the filename of the templates that need an organization snapshot.
+// They are left out of user snapshots unless they are listed explicitly.
+const OrgCardPrefix = "org-"
+
+// OrgCards are the cards rendered for an organization snapshot when no cards are listed explicitly.
+// The other default templates show metrics that only exist for users.
+var OrgCards = []string{"org-overview.svg", "languages.svg", "top-repos.svg"}
+
+// TeamCardPrefix starts the filename of the templates that need a team leaderboard.
+// They are only rendered for leaderboards, which render nothing else unless cards are listed explicitly.
+const TeamCardPrefix = "team-"
+
+// Funcs returns the helper functions available to every card template.
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		"humanize": humanizeNumber,
+		"percent": func(v float64) string {
+			return fmt.Sprintf("%.2f%%", v)
+		},
+		"delta": formatDelta,
+		"date":  formatDate,
+		"add": func(a, b int) int {
+			return a + b
+		},
+		"mul": func(a, b int) int {
+			return a * b
+		},
+	}
+}
+
+// humanizeNumber formats any integer with thousands separators, e.g. 1204 -> "1,204".
+// An unavailable Metric is formatted as Placeholder.
+func humanizeNumber(v any) (string, error) {
+	switch n := v.(type) {
+	case int:
+		return humanize.Comma(int64(n)), nil
+	case int64:
+		return humanize.Comma(n), nil
+	case Metric:
+		return n.String(), nil
+	default:
+		return "", fmt.Errorf("humanize: unsupported type %T", v)
+	}
+}
+
+// formatDelta formats a change with a direction marker, e.g. 12 -> "▲12" and -3 -> "▼3".
+func formatDelta(v any) (string, error) {
+	var n int64
+	switch d := v.(type) {
+	case int:
+		n = int64(d)
+	case int64:
+		n = d
+	default:
+		return "", fmt.Errorf("delta: unsupported type %T", v)
+	}
+
+	switch {
+	case n > 0:
+		return "▲" + humanize.Comma(n), nil
+	case n < 0:
+		return "▼" + humanize.Comma(-n), nil
+	default:
+		return "±0", nil
+	}
+}
+
+// formatDate reformats a YYYY-MM-DD date with the given layout, e.g. formatDate("Jan 2", "2025-01-31") -> "Jan 31".
+// Empty or invalid dates are returned unchanged.
+func formatDate(layout string, value string) string {
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return value
+	}
+	return date.Format(layout)
+}
+
+// ParseFile parses the card template at path with the helper functions available.
+func ParseFile(path string) (*template.Template, error) {
+	tmpl, err := template.New(filepath.Base(path)).Funcs(Funcs()).ParseFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+	}
+	return tmpl, nil
+}
+
+// Render executes a parsed card template against data and writes the result to w.
+func Render(w io.Writer, tmpl *template.Template, data Data) error {
+	if err := tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render %s: %w", tmpl.Name(), err)
+	}
+	return nil
+}
+
+// RenderFile renders the template at templatePath against data and writes the result to outputPath.
+// The card is rendered in memory first, so a failing template does not leave a truncated file behind.
+func RenderFile(templatePath string, outputPath string, data Data) error {
+	tmpl, err := ParseFile(templatePath)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := Render(&buf, tmpl, data); err != nil {
+		return err
+	}
+
+	return os.WriteFile(outputPath, buf.Bytes(), 0644)
+}
+
+// RenderDir renders every template file found directly inside templatesDir against data, or only the ones named in cards if it is not empty.
+// Without cards, organization snapshots render OrgCards, team leaderboards every TeamCardPrefix template
+// and user snapshots every template but the OrgCardPrefix and TeamCardPrefix ones.
+// Each result is written to outputDir under the same filename as its template.
+// It returns the paths of the rendered files.
+func RenderDir(templatesDir string, outputDir string, cards []string, data Data) ([]string, error) {
+	if len(cards) == 0 && data.Organization != nil {
+		cards = OrgCards
+	}
+
+	entries, err := os.ReadDir(templatesDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates directory: %w", err)
+	}
+
+	var rendered []string
+	found := make(map[string]bool)
+	for _, entry := range entries {
+		// Skip nested directories and hidden files such as .gitkeep
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if len(cards) > 0 && !slices.Contains(cards, entry.Name()) {
+			continue
+		}
+		if len(cards) == 0 && !defaultCard(entry.Name(), data) {
+			continue
+		}
+		found[entry.Name()] = true
+
+		outputPath := filepath.Join(outputDir, entry.Name())
+		if err := RenderFile(filepath.Join(templatesDir, entry.Name()), outputPath, data); err != nil {
+			return rendered, err
+		}
+		rendered = append(rendered, outputPath)
+	}
+
+	for _, card := range cards {
+		if !found[card] {
+			return rendered, fmt.Errorf("card %s has no template in %s", card, templatesDir)
+		}
+	}
+
+	return rendered, nil
+}
+
+// defaultCard reports whether a template is rendered for data when no cards are listed.
+func defaultCard(name string, data Data) bool {
+	if data.Leaderboard != nil {
+		return strings.HasPrefix(name, TeamCardPrefix)
+	}
+	return !strings.HasPrefix(name, OrgCardPrefix) && !strings.HasPrefix(name, TeamCardPrefix)
+}
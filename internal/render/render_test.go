@@ -0,0 +1,321 @@
+package render
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"snapshot/internal/history"
+	"snapshot/internal/snapshot"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the current output")
+
+const templatesDir = "../../templates"
+
+func metric(v int64) Metric {
+	return Metric{Value: v, OK: true}
+}
+
+// baseData is a typical user without profile views, shared by every golden data set.
+func baseData() Data {
+	var days []snapshot.ContributionDay
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 366 {
+		date := start.AddDate(0, 0, i)
+		count := (i * 7) % 11
+		level := []string{"NONE", "FIRST_QUARTILE", "SECOND_QUARTILE", "THIRD_QUARTILE", "FOURTH_QUARTILE"}[count%5]
+		if count == 0 {
+			level = "NONE"
+		}
+		days = append(days, snapshot.ContributionDay{Date: date.Format(dateLayout), Count: count, Level: level})
+	}
+
+	return Data{
+		Name:               "The Octocat",
+		Theme:              "auto",
+		Stars:              1204,
+		Forks:              87,
+		Contributions:      metric(3120),
+		LinesChanged:       metric(308556),
+		Repos:              42,
+		Views:              metric(310),
+		PullRequests:       metric(312),
+		MergedPullRequests: metric(280),
+		Issues:             metric(95),
+		ClosedIssues:       metric(71),
+		Reviews:            metric(140),
+		Languages: []Language{
+			{Name: "Go", Colour: "#00ADD8", Size: 5120, Occurrences: 12, Percent: 51.2},
+			{Name: "TypeScript", Colour: "#3178c6", Size: 3000, Occurrences: 5, Percent: 30},
+			{Name: "Shell", Colour: "#89e051", Size: 1880, Occurrences: 9, Percent: 18.8},
+		},
+		TopRepos: []Repo{
+			{NameWithOwner: "octocat/hello-world", Owner: "octocat", Name: "hello-world", Language: "Go", Colour: "#00ADD8", Stars: 900, Forks: 40, Value: 900},
+			{NameWithOwner: "octocat/spoon-knife", Owner: "octocat", Name: "spoon-knife", Language: "HTML", Colour: "#e34c26", Stars: 300, Forks: 47, Value: 300},
+		},
+		TopReposMetric: "stars",
+		Heatmap:        NewHeatmap(days, 2024, time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC)),
+		CurrentStreak:  Streak{Length: metric(3), Start: "2025-01-29", End: "2025-01-31"},
+		LongestStreak:  Streak{Length: metric(40), Start: "2024-01-01", End: "2024-02-09"},
+		ActiveDays:     metric(612),
+	}
+}
+
+var goldenData = map[string]func() Data{
+	"default": baseData,
+	"no-languages": func() Data {
+		data := baseData()
+		data.Languages = nil
+		data.TopRepos = nil
+		return data
+	},
+	"many-languages": func() Data {
+		data := baseData()
+		data.Languages = nil
+		for i := range 20 {
+			data.Languages = append(data.Languages, Language{
+				Name:        fmt.Sprintf("Language %d", i+1),
+				Colour:      fmt.Sprintf("#%02x%02x%02x", i*12, 255-i*12, 128),
+				Size:        2000 - i*100,
+				Occurrences: 20 - i,
+				Percent:     float64(2000-i*100) / 210,
+			})
+		}
+		return data
+	},
+	"profile-views": func() Data {
+		data := baseData()
+		data.IncludeProfileViews = true
+		data.ProfileViews = metric(1500)
+		trend := history.Delta{Days: 7, Label: "this week", Since: "2025-01-24", Stars: 12, Forks: -1, Contributions: 40, ProfileViews: 25}
+		data.Trend = &trend
+		data.Trends = []history.Delta{trend}
+		return data
+	},
+	"dark": func() Data {
+		data := baseData()
+		data.Theme = "dark"
+		return data
+	},
+	"light": func() Data {
+		data := baseData()
+		data.Theme = "light"
+		return data
+	},
+	"long-names": func() Data {
+		data := baseData()
+		data.IncludeProfileViews = true
+		data.ProfileViews = metric(1500)
+		data.Name = "Maximiliana Alexandria Montgomery-Worthington <the \"Third\"> & Co."
+		data.Languages[0].Name = "Jupyter Notebook With An Unreasonably Long Language Name"
+		data.TopRepos[0].NameWithOwner = "an-organisation-with-a-long-name/a-repository-with-an-even-longer-name-than-that"
+		data.TopRepos[0].Owner = "an-organisation-with-a-long-name"
+		data.TopRepos[0].Name = "a-repository-with-an-even-longer-name-than-that"
+		return data
+	},
+	"organization": func() Data {
+		data := baseData()
+		data.Name = "Octo Org"
+		data.Organization = &Organization{
+			Login:        "octo-org",
+			Description:  "Home of the octocats & friends",
+			Avatar:       "data:image/png;base64,iVBORw0KGgo=",
+			Contributors: metric(57),
+			TopContributors: []Contributor{
+				{Login: "octocat", Contributions: 1200},
+				{Login: "hubot", Contributions: 640},
+				{Login: "monalisa", Contributions: 310},
+				{Login: "defunkt", Contributions: 12},
+			},
+		}
+		data.Contributions = Metric{}
+		data.ActiveDays = Metric{}
+		data.CurrentStreak = Streak{}
+		data.LongestStreak = Streak{}
+		data.Heatmap = Heatmap{}
+		return data
+	},
+	"team": func() Data {
+		members := []Member{
+			{Login: "octocat", Name: "The Octocat", Avatar: "data:image/png;base64,iVBORw0KGgo=", RecentContributions: metric(42), MergedPullRequests: metric(12), LinesChanged: metric(20480)},
+			{Login: "hubot", Name: "Hubot <bot> & Friends", RecentContributions: metric(42), MergedPullRequests: metric(30), LinesChanged: metric(1200)},
+			{Login: "monalisa", Name: "Mona Lisa Octocat With A Rather Long Display Name", RecentContributions: metric(7), MergedPullRequests: metric(3), LinesChanged: Metric{}},
+			{Login: "defunkt", Name: "defunkt", RecentContributions: Metric{}, MergedPullRequests: Metric{}, LinesChanged: Metric{}},
+		}
+		leaderboard := NewLeaderboard(members, LeaderboardMetrics, 0)
+		return Data{Name: "Team Leaderboard", Theme: "auto", Leaderboard: &leaderboard}
+	},
+	"unavailable": func() Data {
+		data := baseData()
+		data.IncludeProfileViews = true
+		data.Contributions = Metric{}
+		data.LinesChanged = Metric{}
+		data.Views = Metric{}
+		data.ProfileViews = Metric{}
+		data.ActiveDays = Metric{}
+		data.CurrentStreak = Streak{}
+		data.LongestStreak = Streak{}
+		data.Heatmap = Heatmap{}
+		return data
+	},
+}
+
+// goldenExtraCards are rendered for a data set on top of the cards RenderDir selects for it by default.
+// The default user data set keeps the placeholder of the organization overview without an organization.
+var goldenExtraCards = map[string][]string{
+	"default": {"org-overview.svg"},
+}
+
+// goldenCard reports whether the golden files of a data set include a template.
+// Like RenderDir without cards, organizations render OrgCards and team templates are only rendered for the team leaderboard.
+func goldenCard(dataset string, name string, data Data) bool {
+	if slices.Contains(goldenExtraCards[dataset], name) {
+		return true
+	}
+	if data.Organization != nil {
+		return slices.Contains(OrgCards, name)
+	}
+	return defaultCard(name, data)
+}
+
+// TestGolden renders every card template against the data sets it applies to and compares the result to testdata/golden.
+// Run with -update to accept the current output, which also removes the golden files no data set renders anymore.
+func TestGolden(t *testing.T) {
+	entries, err := os.ReadDir(templatesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rendered := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		tmpl, err := ParseFile(filepath.Join(templatesDir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for name, data := range goldenData {
+			if !goldenCard(name, entry.Name(), data()) {
+				continue
+			}
+			golden := filepath.Join("testdata", "golden", name, entry.Name())
+			rendered[golden] = true
+
+			t.Run(name+"/"+entry.Name(), func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := Render(&buf, tmpl, data()); err != nil {
+					t.Fatal(err)
+				}
+
+				if *update {
+					if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatalf("%v (run go test ./internal/render -update to create it)", err)
+				}
+				if !bytes.Equal(buf.Bytes(), want) {
+					t.Errorf("%s differs from %s, run go test ./internal/render -update if the change is intended\n%s", entry.Name(), golden, firstDiff(buf.String(), string(want)))
+				}
+			})
+		}
+	}
+
+	stale, err := filepath.Glob(filepath.Join("testdata", "golden", "*", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, golden := range stale {
+		if rendered[golden] {
+			continue
+		}
+		if *update {
+			if err := os.Remove(golden); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		t.Errorf("%s is not rendered by any data set, run go test ./internal/render -update to remove it", golden)
+	}
+}
+
+// firstDiff describes the first line where got and want differ.
+func firstDiff(got string, want string) string {
+	gotLines := strings.Split(got, "\n")
+	wantLines := strings.Split(want, "\n")
+	for i := range max(len(gotLines), len(wantLines)) {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			return fmt.Sprintf("line %d:\n  got:  %s\n  want: %s", i+1, g, w)
+		}
+	}
+	return ""
+}
+
+func TestRenderFileDoesNotTruncateOnError(t *testing.T) {
+	dir := t.TempDir()
+	templatePath := filepath.Join(dir, "broken.svg")
+	outputPath := filepath.Join(dir, "out.svg")
+	if err := os.WriteFile(templatePath, []byte(`<svg>{{ humanize .Name }}</svg>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(outputPath, []byte("previous card"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RenderFile(templatePath, outputPath, baseData()); err == nil {
+		t.Fatal("expected humanize to reject a string")
+	}
+	if dat, _ := os.ReadFile(outputPath); string(dat) != "previous card" {
+		t.Errorf("output was overwritten with %q", dat)
+	}
+}
+
+func TestRenderDirDefaultCards(t *testing.T) {
+	for name, want := range map[string][]string{
+		"default":      {"heatmap.svg", "languages.svg", "overview.svg", "streak.svg", "top-repos.svg"},
+		"organization": OrgCards,
+		"team":         {"team-leaderboard.svg"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			outputDir := t.TempDir()
+			if _, err := RenderDir(templatesDir, outputDir, nil, goldenData[name]()); err != nil {
+				t.Fatal(err)
+			}
+
+			entries, err := os.ReadDir(outputDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, entry := range entries {
+				got = append(got, entry.Name())
+			}
+			if !slices.Equal(got, slices.Sorted(slices.Values(want))) {
+				t.Errorf("rendered %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"snapshot/internal/helpers"
+	"snapshot/internal/snapshot"
+)
+
+// RepoMetrics are the metrics repositories can be ranked by on the top repositories card.
+var RepoMetrics = []string{"stars", "forks", "lines", "views"}
+
+// Repo is a single counted repository as exposed to templates.
+type Repo struct {
+	NameWithOwner string
+	Name          string
+	Owner         string
+	Language      string // Primary language, empty if GitHub could not detect one
+	Colour        string // Colour of the primary language
+	Stars         int
+	Forks         int
+	Additions     int // Lines added by the user on the default branch
+	Deletions     int // Lines deleted by the user on the default branch
+	LinesChanged  int
+	Views         int
+	Value         int // Value of the metric the repository was ranked by
+}
+
+// ValidateRepoMetric returns an error if repos cannot be ranked by the given metric.
+func ValidateRepoMetric(metric string) error {
+	for _, m := range RepoMetrics {
+		if m == metric {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown repository metric %q, expected one of %s", metric, strings.Join(RepoMetrics, ", "))
+}
+
+// NewRepos collects every counted repository of the snapshot with its lines changed and views.
+// Lines changed or views that failed to compute are left at zero, only a fatal error is returned.
+func NewRepos(s *snapshot.Snapshot) ([]Repo, error) {
+	counted, err := snapshot.GetRepos(s)
+	if err != nil {
+		return nil, err
+	}
+	repoLines, err := snapshot.GetRepoLinesChanged(s)
+	if snapshot.IsFatal(err) {
+		return nil, err
+	}
+	repoViews, err := snapshot.GetRepoViews(s)
+	if snapshot.IsFatal(err) {
+		return nil, err
+	}
+
+	repos := make([]Repo, 0, len(counted))
+	for nameWithOwner, r := range counted {
+		owner, name, err := helpers.SplitOwnerRepo(nameWithOwner)
+		if err != nil {
+			continue
+		}
+
+		colour := r.PrimaryLanguage.Color
+		if colour == "" {
+			colour = "#000000"
+		}
+
+		lines := repoLines[nameWithOwner]
+		repos = append(repos, Repo{
+			NameWithOwner: nameWithOwner,
+			Name:          name,
+			Owner:         owner,
+			Language:      r.PrimaryLanguage.Name,
+			Colour:        colour,
+			Stars:         r.Stargazers.TotalCount,
+			Forks:         r.ForkCount,
+			Additions:     lines[0],
+			Deletions:     lines[1],
+			LinesChanged:  lines[0] + lines[1],
+			Views:         repoViews[nameWithOwner],
+		})
+	}
+
+	return repos, nil
+}
+
+// TopRepos ranks repos by metric, largest first, and returns at most n of them.
+// Repositories with nothing to show for the metric are left out.
+func TopRepos(repos []Repo, metric string, n int) []Repo {
+	ranked := make([]Repo, 0, len(repos))
+	for _, repo := range repos {
+		switch metric {
+		case "forks":
+			repo.Value = repo.Forks
+		case "lines":
+			repo.Value = repo.LinesChanged
+		case "views":
+			repo.Value = repo.Views
+		default:
+			repo.Value = repo.Stars
+		}
+
+		if repo.Value > 0 {
+			ranked = append(ranked, repo)
+		}
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Value != ranked[j].Value {
+			return ranked[i].Value > ranked[j].Value
+		}
+		return strings.ToLower(ranked[i].NameWithOwner) < strings.ToLower(ranked[j].NameWithOwner)
+	})
+
+	if n > 0 && len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
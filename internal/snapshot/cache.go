@@ -0,0 +1,101 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LinesCacheVersion is bumped whenever the cache layout changes, which discards older caches.
+const LinesCacheVersion = 1
+
+// LinesCache remembers, per repo, the newest commit of the default branch that was already counted
+// along with the user's cumulative additions and deletions up to that commit.
+// Subsequent runs only walk the commits made since, instead of the full history.
+type LinesCache struct {
+	Version int                   `json:"version"`
+	User    string                `json:"user"`
+	Repos   map[string]RepoCursor `json:"repos"`
+
+	mu sync.Mutex
+}
+
+type RepoCursor struct {
+	HeadOID   string    `json:"headOid"`
+	Additions int       `json:"additions"`
+	Deletions int       `json:"deletions"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// NewLinesCache creates an empty cache for the given user.
+func NewLinesCache(user string) *LinesCache {
+	return &LinesCache{
+		Version: LinesCacheVersion,
+		User:    user,
+		Repos:   make(map[string]RepoCursor),
+	}
+}
+
+// LoadLinesCache reads the cache at path. A missing cache, a cache of an older version
+// or a cache built for a different user results in an empty cache.
+func LoadLinesCache(path string, user string) (*LinesCache, error) {
+	dat, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewLinesCache(user), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cache := NewLinesCache(user)
+	if err := json.Unmarshal(dat, cache); err != nil {
+		return nil, fmt.Errorf("invalid lines changed cache %s: %w", path, err)
+	}
+
+	if cache.Version != LinesCacheVersion || cache.User != user || cache.Repos == nil {
+		return NewLinesCache(user), nil
+	}
+
+	return cache, nil
+}
+
+// Save writes the cache to path, creating its directory if needed.
+func (c *LinesCache) Save(path string) error {
+	c.mu.Lock()
+	dat, err := json.MarshalIndent(c, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, dat, 0644)
+}
+
+// Get returns the cursor of a repo, if it has been counted before.
+func (c *LinesCache) Get(nameWithOwner string) (RepoCursor, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cursor, ok := c.Repos[nameWithOwner]
+	return cursor, ok
+}
+
+// Put stores the cursor of a repo. It is safe to call from concurrent workers.
+func (c *LinesCache) Put(nameWithOwner string, cursor RepoCursor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Repos[nameWithOwner] = cursor
+}
+
+// SetLinesCache makes GetLinesChanged resume from the cursors in cache and record the new ones in it.
+func SetLinesCache(self *Snapshot, cache *LinesCache) {
+	self.linesCache = cache
+}
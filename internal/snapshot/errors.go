@@ -0,0 +1,45 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MetricError is returned by a getter whose metric could not be computed.
+// Fatal errors affect the repositories every card is built from, so nothing meaningful can be rendered.
+// Other errors only affect their own metric, which can be replaced by a placeholder.
+type MetricError struct {
+	Metric string
+	Fatal  bool
+	Err    error
+}
+
+func (e *MetricError) Error() string {
+	return fmt.Sprintf("failed to get %s: %s", e.Metric, e.Err)
+}
+
+func (e *MetricError) Unwrap() error {
+	return e.Err
+}
+
+func fatalError(metric string, err error) error {
+	return &MetricError{Metric: metric, Fatal: true, Err: err}
+}
+
+func degradedError(metric string, err error) error {
+	return &MetricError{Metric: metric, Fatal: false, Err: err}
+}
+
+// IsFatal reports whether err prevents the snapshot from being rendered at all.
+// Errors that are not a MetricError are treated as fatal.
+func IsFatal(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var metricErr *MetricError
+	if errors.As(err, &metricErr) {
+		return metricErr.Fatal
+	}
+	return true
+}
@@ -0,0 +1,249 @@
+package snapshot
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExportSchemaVersion is bumped whenever a field in Export is renamed, removed or changes meaning.
+// Adding new fields does not require a bump.
+const ExportSchemaVersion = 1
+
+// Export is the machine-readable form of a snapshot, written to snapshot.json.
+type Export struct {
+	SchemaVersion int                `json:"schemaVersion"`
+	GeneratedAt   time.Time          `json:"generatedAt"`
+	User          string             `json:"user"` // Login of the user, or of the organization in organization mode
+	Name          string             `json:"name"`
+	Organization  *Organization      `json:"organization,omitempty"` // Only set in organization mode
+	Totals        ExportTotals       `json:"totals"`
+	Streaks       Streaks            `json:"streaks"`
+	Calendar      []ContributionDay  `json:"contributionCalendar"` // Days with at least one contribution, sorted by date
+	Languages     []ExportLanguage   `json:"languages"`
+	Repos         []ExportRepo       `json:"repos"`
+	Contributors  []ContributorCount `json:"contributors,omitempty"` // Only set in organization mode, most commits first
+	Unavailable   []string           `json:"unavailable,omitempty"`  // Keys of the metrics that failed to compute and are left at zero
+}
+
+type ExportTotals struct {
+	Stars         int   `json:"stars"`
+	Forks         int   `json:"forks"`
+	Contributions int   `json:"contributions"`
+	Additions     int   `json:"additions"`
+	Deletions     int   `json:"deletions"`
+	LinesChanged  int64 `json:"linesChanged"`
+	Repos         int   `json:"repos"`
+	Views         int   `json:"views"`
+	ProfileViews  *int  `json:"profileViews,omitempty"`
+	Contributors  int   `json:"contributors,omitempty"` // Only set in organization mode
+
+	PullRequests       int `json:"pullRequests"`
+	MergedPullRequests int `json:"mergedPullRequests"`
+	Issues             int `json:"issues"`
+	ClosedIssues       int `json:"closedIssues"`
+	Reviews            int `json:"reviews"`
+}
+
+type ExportLanguage struct {
+	Name        string  `json:"name"`
+	Colour      string  `json:"colour"`
+	Size        int     `json:"size"`
+	Occurrences int     `json:"occurrences"`
+	Percent     float64 `json:"percent"`
+}
+
+type ExportRepo struct {
+	NameWithOwner string               `json:"nameWithOwner"`
+	IsFork        bool                 `json:"isFork"`
+	Language      string               `json:"language,omitempty"` // Primary language
+	Colour        string               `json:"colour,omitempty"`   // Colour of the primary language
+	Stars         int                  `json:"stars"`
+	Forks         int                  `json:"forks"`
+	Additions     int                  `json:"additions"`
+	Deletions     int                  `json:"deletions"`
+	Views         int                  `json:"views"`
+	Languages     []ExportRepoLanguage `json:"languages"`
+}
+
+type ExportRepoLanguage struct {
+	Name   string `json:"name"`
+	Colour string `json:"colour"`
+	Size   int    `json:"size"`
+}
+
+// NewExport collects every computed metric of the snapshot, including per-repo breakdowns, into an Export.
+// Metrics that have not been computed yet are fetched.
+// Metrics that failed without being fatal are left at zero and listed in Unavailable.
+// In organization mode, the metrics that only exist for users are left at zero without being listed.
+// A fatal error is returned as is.
+func NewExport(self *Snapshot) (Export, error) {
+	name, err := GetName(self)
+	if err != nil {
+		return Export{}, err
+	}
+	repos, err := GetRepos(self)
+	if err != nil {
+		return Export{}, err
+	}
+	languages, err := GetLanguages(self)
+	if err != nil {
+		return Export{}, err
+	}
+
+	export := Export{
+		SchemaVersion: ExportSchemaVersion,
+		GeneratedAt:   time.Now().UTC(),
+		User:          GetLogin(self),
+		Name:          name,
+		Totals: ExportTotals{
+			Stars: *self._stargazers,
+			Forks: *self._forks,
+			Repos: len(repos),
+		},
+		Calendar:  []ContributionDay{},
+		Languages: []ExportLanguage{},
+		Repos:     []ExportRepo{},
+	}
+
+	// unavailable records a degraded metric, while fatal errors abort the export
+	unavailable := func(err error, keys ...string) error {
+		if IsFatal(err) {
+			return err
+		}
+		log.Printf("Exporting without %s: %s", strings.Join(keys, ", "), err)
+		export.Unavailable = append(export.Unavailable, keys...)
+		return nil
+	}
+
+	if self.organization != "" {
+		if err := exportOrganization(self, &export, unavailable); err != nil {
+			return Export{}, err
+		}
+	} else if err := exportContributions(self, &export, unavailable); err != nil {
+		return Export{}, err
+	}
+
+	repoLines, err := GetRepoLinesChanged(self)
+	if err == nil {
+		export.Totals.Additions = self._linesChanged[0]
+		export.Totals.Deletions = self._linesChanged[1]
+		export.Totals.LinesChanged = int64(self._linesChanged[0] + self._linesChanged[1])
+	} else if err := unavailable(err, "additions", "deletions", "linesChanged"); err != nil {
+		return Export{}, err
+	}
+
+	repoViews, err := GetRepoViews(self)
+	if err == nil {
+		export.Totals.Views = *self._views
+	} else if err := unavailable(err, "views"); err != nil {
+		return Export{}, err
+	}
+
+	if self.IncludeProfileViews {
+		if profileViews, err := GetProfileViews(self); err == nil {
+			export.Totals.ProfileViews = &profileViews
+		} else if err := unavailable(err, "profileViews"); err != nil {
+			return Export{}, err
+		}
+	}
+
+	for name, info := range languages {
+		export.Languages = append(export.Languages, ExportLanguage{
+			Name:        name,
+			Colour:      info.Colour,
+			Size:        info.Size,
+			Occurrences: info.Occurrences,
+			Percent:     info.Prop,
+		})
+	}
+	sort.Slice(export.Languages, func(i, j int) bool {
+		return export.Languages[i].Size > export.Languages[j].Size
+	})
+
+	for nameWithOwner, repo := range repos {
+		lines := repoLines[nameWithOwner]
+		entry := ExportRepo{
+			NameWithOwner: nameWithOwner,
+			IsFork:        repo.IsFork,
+			Language:      repo.PrimaryLanguage.Name,
+			Colour:        repo.PrimaryLanguage.Color,
+			Stars:         repo.Stargazers.TotalCount,
+			Forks:         repo.ForkCount,
+			Additions:     lines[0],
+			Deletions:     lines[1],
+			Views:         repoViews[nameWithOwner],
+			Languages:     []ExportRepoLanguage{},
+		}
+
+		for _, langEdge := range repo.Languages.Edges {
+			entry.Languages = append(entry.Languages, ExportRepoLanguage{
+				Name:   langEdge.Node.Name,
+				Colour: langEdge.Node.Color,
+				Size:   langEdge.Size,
+			})
+		}
+
+		export.Repos = append(export.Repos, entry)
+	}
+	sort.Slice(export.Repos, func(i, j int) bool {
+		return strings.ToLower(export.Repos[i].NameWithOwner) < strings.ToLower(export.Repos[j].NameWithOwner)
+	})
+
+	return export, nil
+}
+
+// exportContributions adds the metrics computed from the user's contribution calendar to the export.
+func exportContributions(self *Snapshot, export *Export, unavailable func(error, ...string) error) error {
+	if contributions, err := GetContributions(self); err == nil {
+		export.Totals.Contributions = contributions
+	} else if err := unavailable(err, "contributions"); err != nil {
+		return err
+	}
+
+	if activity, err := GetActivity(self); err == nil {
+		export.Totals.PullRequests = activity.PullRequests
+		export.Totals.MergedPullRequests = activity.MergedPullRequests
+		export.Totals.Issues = activity.Issues
+		export.Totals.ClosedIssues = activity.ClosedIssues
+		export.Totals.Reviews = activity.Reviews
+	} else if err := unavailable(err, "pullRequests", "mergedPullRequests", "issues", "closedIssues", "reviews"); err != nil {
+		return err
+	}
+
+	if streaks, err := GetStreaks(self); err == nil {
+		export.Streaks = streaks
+	} else if err := unavailable(err, "streaks"); err != nil {
+		return err
+	}
+
+	// Days without contributions are left out to keep the file small, they are implied by the gaps between dates
+	if days, err := GetContributionCalendar(self); err == nil {
+		for _, day := range days {
+			if day.Count > 0 {
+				export.Calendar = append(export.Calendar, day)
+			}
+		}
+	} else if err := unavailable(err, "contributionCalendar"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// exportOrganization adds the organization and its contributors to the export.
+func exportOrganization(self *Snapshot, export *Export, unavailable func(error, ...string) error) error {
+	org, err := GetOrganization(self)
+	if err != nil {
+		return err
+	}
+	export.Organization = &org
+
+	if contributors, err := GetContributors(self); err == nil {
+		export.Contributors = contributors
+		export.Totals.Contributors = len(contributors)
+	} else if err := unavailable(err, "contributors"); err != nil {
+		return err
+	}
+	return nil
+}
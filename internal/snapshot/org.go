@@ -0,0 +1,186 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+
+	"snapshot/internal/helpers"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+// errOrganization is reported for metrics that only exist for users, such as the contribution calendar.
+var errOrganization = errors.New("not available for organizations")
+
+// getOrgStats collects the stats of every repository of the organization, forks included unless filtered out.
+// The organization's avatar is fetched once, a failure only leaves the avatar empty.
+func getOrgStats(self *Snapshot) error {
+	repoCursor := graphql.String("")
+	for {
+		var query OrgReposQuery
+		vars := map[string]any{
+			"login":      graphql.String(self.organization),
+			"repoCursor": repoCursor,
+		}
+		if err := helpers.RunQuery(self.queryClient, &query, vars); err != nil {
+			return err
+		}
+
+		org := query.Organization
+		if org.Login == "" {
+			return fmt.Errorf("organization %s not found", self.organization)
+		}
+		if self._organization == nil {
+			self._organization = &Organization{
+				Login:       org.Login,
+				Name:        org.Name,
+				Description: org.Description,
+			}
+			name := org.Name
+			if name == "" {
+				name = org.Login
+			}
+			self._name = &name
+
+			if org.AvatarUrl != "" {
+				avatar, err := helpers.FetchDataURI(self.plainClient, org.AvatarUrl)
+				if err != nil {
+					log.Printf("Failed to get the avatar of %s: %v", org.Login, err)
+				}
+				self._organization.Avatar = avatar
+			}
+		}
+
+		for _, repo := range org.Repositories.Nodes {
+			countRepo(self, repo)
+		}
+
+		repoCursor = graphql.String(org.Repositories.PageInfo.EndCursor)
+		if !org.Repositories.PageInfo.HasNextPage {
+			break
+		}
+	}
+
+	return nil
+}
+
+// GetOrganization returns the organization an organization snapshot is generated for.
+// It fails for user snapshots.
+func GetOrganization(self *Snapshot) (Organization, error) {
+	if self.organization == "" {
+		return Organization{}, fmt.Errorf("%s is a user snapshot, not an organization one", self.user)
+	}
+	if self._organization != nil {
+		return *self._organization, nil
+	}
+
+	if err := getStats(self); err != nil {
+		return Organization{}, err
+	}
+	return *self._organization, nil
+}
+
+// IsOrganization reports whether the snapshot is generated for an organization instead of a user.
+func IsOrganization(self *Snapshot) bool {
+	return self.organization != ""
+}
+
+// GetContributors returns everyone who committed to the counted repos, most commits first.
+// If the contributors of any repo cannot be read, the metric fails as a whole instead of reporting a partial list.
+func GetContributors(self *Snapshot) ([]ContributorCount, error) {
+	if self._contributors != nil {
+		return self._contributors, nil
+	}
+	if self._contributorsErr != nil {
+		return nil, self._contributorsErr
+	}
+
+	counted, err := GetRepos(self)
+	if err != nil {
+		return nil, err
+	}
+
+	repos := make([]string, 0, len(counted))
+	for repo := range counted {
+		repos = append(repos, repo)
+	}
+
+	// Fetch every repo's contributors concurrently, then aggregate the results on this goroutine
+	type repoResult struct {
+		contributors []ContributorCount
+		err          error
+	}
+	results := helpers.RunPool(self.workers, repos, func(repo string) repoResult {
+		contributors, err := getRepoContributors(self, repo)
+		return repoResult{contributors, err}
+	})
+
+	var errs []error
+	byLogin := make(map[string]int)
+	for _, result := range results {
+		if result.err != nil {
+			errs = append(errs, result.err)
+			continue
+		}
+		for _, contributor := range result.contributors {
+			byLogin[contributor.Login] += contributor.Contributions
+		}
+	}
+
+	if len(errs) > 0 {
+		self._contributorsErr = degradedError("contributors", errors.Join(errs...))
+		return nil, self._contributorsErr
+	}
+
+	contributors := make([]ContributorCount, 0, len(byLogin))
+	for login, contributions := range byLogin {
+		contributors = append(contributors, ContributorCount{Login: login, Contributions: contributions})
+	}
+	sort.Slice(contributors, func(i, j int) bool {
+		if contributors[i].Contributions != contributors[j].Contributions {
+			return contributors[i].Contributions > contributors[j].Contributions
+		}
+		return contributors[i].Login < contributors[j].Login
+	})
+
+	self._contributors = contributors
+	return contributors, nil
+}
+
+// getRepoContributors pages through the contributors of a single repo.
+// Commits by authors without a GitHub account are not listed by GitHub, so they are not counted.
+func getRepoContributors(self *Snapshot, repo string) ([]ContributorCount, error) {
+	const perPage = 100
+	uri := fmt.Sprintf("repos/%s/contributors", repo)
+
+	var contributors []ContributorCount
+	for page := 1; ; page++ {
+		response, err := helpers.RunRestQuery(self.client, self.endpoints.REST, uri, map[string]string{
+			"per_page": strconv.Itoa(perPage),
+			"page":     strconv.Itoa(page),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("contributors of %s: %w", repo, err)
+		}
+		// Empty repos answer 204 No Content
+		if len(response) == 0 {
+			break
+		}
+
+		var batch []ContributorCount
+		if err := json.Unmarshal(response, &batch); err != nil {
+			return nil, fmt.Errorf("failed to decode contributors of %s: %w", repo, err)
+		}
+		contributors = append(contributors, batch...)
+
+		if len(batch) < perPage {
+			break
+		}
+	}
+
+	return contributors, nil
+}
@@ -0,0 +1,104 @@
+package snapshot
+
+import (
+	"encoding/base64"
+	"reflect"
+	"slices"
+	"testing"
+
+	"snapshot/internal/githubtest"
+)
+
+func TestOrganization(t *testing.T) {
+	server := newTestServer(t)
+	server.OrgName = "Octo Org"
+	server.OrgDescription = "Home of the octocats"
+	s := newTestSnapshot(server, Options{
+		Organization:        "octo-org",
+		IncludeForkedRepos:  true,
+		IncludeProfileViews: true,
+		ExcludedRepos:       map[string]struct{}{"octocat/spoon-knife": {}},
+		ExcludedLangs:       map[string]struct{}{"html": {}},
+	})
+
+	export, err := NewExport(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Organization{
+		Login:       "octo-org",
+		Name:        "Octo Org",
+		Description: "Home of the octocats",
+		Avatar:      "data:image/png;base64," + base64.StdEncoding.EncodeToString(githubtest.Avatar),
+	}
+	if export.Organization == nil || *export.Organization != want {
+		t.Errorf("organization = %+v, want %+v", export.Organization, want)
+	}
+	if export.User != "octo-org" || export.Name != "Octo Org" {
+		t.Errorf("user, name = %q, %q, want octo-org, Octo Org", export.User, export.Name)
+	}
+
+	// Forks of the organization are counted when included, external repos are never part of it
+	if export.Totals.Stars != 110 || export.Totals.Repos != 2 {
+		t.Errorf("stars, repos = %d, %d, want 110, 2", export.Totals.Stars, export.Totals.Repos)
+	}
+	if len(export.Languages) != 1 || export.Languages[0].Name != "Go" {
+		t.Errorf("languages = %+v, want only Go", export.Languages)
+	}
+
+	// Lines are counted for every author, not only the token holder
+	if export.Totals.Additions != 115 || export.Totals.Deletions != 103 {
+		t.Errorf("additions, deletions = %d, %d, want 115, 103", export.Totals.Additions, export.Totals.Deletions)
+	}
+
+	wantContributors := []ContributorCount{{Login: "octocat", Contributions: 2}, {Login: "someone", Contributions: 1}}
+	if !reflect.DeepEqual(export.Contributors, wantContributors) || export.Totals.Contributors != 2 {
+		t.Errorf("contributors = %+v (%d), want %+v", export.Contributors, export.Totals.Contributors, wantContributors)
+	}
+
+	// Metrics that only exist for users are neither fetched nor reported as unavailable
+	if len(export.Unavailable) != 0 {
+		t.Errorf("unavailable = %v, want none", export.Unavailable)
+	}
+	if _, err := GetContributions(&s); err == nil || IsFatal(err) {
+		t.Errorf("contributions error = %v, want a degraded error", err)
+	}
+	if export.Totals.ProfileViews != nil {
+		t.Error("profile views were exported for an organization")
+	}
+	for _, kind := range []string{"repositories", "contributionYears", "profileViews"} {
+		if got := server.Requests(kind); got != 0 {
+			t.Errorf("%s requested %d times, want 0", kind, got)
+		}
+	}
+
+	// A restored organization snapshot exports the same data without the network
+	server.Close()
+	restored := Restore(export)
+	if !IsOrganization(&restored) {
+		t.Error("restored snapshot is not an organization snapshot")
+	}
+	again, err := NewExport(&restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again.GeneratedAt = export.GeneratedAt
+	if !reflect.DeepEqual(again, export) {
+		t.Errorf("restored export differs from the saved one:\n%+v\n%+v", again, export)
+	}
+}
+
+func TestOrganizationContributorsFailure(t *testing.T) {
+	server := newTestServer(t)
+	server.FailContributors = true
+	s := newTestSnapshot(server, Options{Organization: "octo-org"})
+
+	export, err := NewExport(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(export.Unavailable, "contributors") || export.Contributors != nil {
+		t.Errorf("unavailable = %v, contributors = %+v, want contributors unavailable", export.Unavailable, export.Contributors)
+	}
+}
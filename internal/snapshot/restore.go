@@ -0,0 +1,181 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+
+	"snapshot/internal/helpers"
+
+	"github.com/hasura/go-graphql-client"
+)
+
+// errNotSaved is reported for metrics that were unavailable when the restored snapshot was saved.
+var errNotSaved = errors.New("unavailable in the saved snapshot")
+
+// WriteExport writes an Export to path as indented JSON.
+func WriteExport(path string, export Export) error {
+	dat, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, dat, 0644)
+}
+
+// Save collects every metric of the snapshot and writes it to path, so it can be restored later with Load.
+func Save(self *Snapshot, path string) (Export, error) {
+	export, err := NewExport(self)
+	if err != nil {
+		return Export{}, err
+	}
+	return export, WriteExport(path, export)
+}
+
+// Load restores a snapshot saved to path, see Restore.
+func Load(path string) (Snapshot, Export, error) {
+	export, err := LoadExport(path)
+	if err != nil {
+		return Snapshot{}, Export{}, err
+	}
+	return Restore(export), export, nil
+}
+
+// LoadExport reads an Export previously written to snapshot.json.
+func LoadExport(path string) (Export, error) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return Export{}, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var export Export
+	if err := json.Unmarshal(dat, &export); err != nil {
+		return Export{}, fmt.Errorf("failed to decode snapshot %s: %w", path, err)
+	}
+	if export.SchemaVersion != ExportSchemaVersion {
+		return Export{}, fmt.Errorf("snapshot %s has schema version %d, expected %d", path, export.SchemaVersion, ExportSchemaVersion)
+	}
+	return export, nil
+}
+
+// Restore rebuilds a Snapshot from an Export, so the getters return the saved metrics.
+// The restored snapshot has no network access: anything that was not saved fails instead of being fetched.
+func Restore(export Export) Snapshot {
+	client := &http.Client{Transport: helpers.OfflineTransport{}}
+	unavailable := func(key string) bool {
+		return slices.Contains(export.Unavailable, key)
+	}
+
+	self := Snapshot{
+		user:                export.User,
+		client:              client,
+		queryClient:         graphql.NewClient("", client),
+		IncludeProfileViews: export.Totals.ProfileViews != nil || unavailable("profileViews"),
+	}
+
+	name := export.Name
+	stargazers := export.Totals.Stars
+	forks := export.Totals.Forks
+	self._name = &name
+	self._stargazers = &stargazers
+	self._forks = &forks
+
+	self._repos = make(map[string]RepoWithLanguages, len(export.Repos))
+	self._repoLinesChanged = make(map[string][2]int, len(export.Repos))
+	self._repoViews = make(map[string]int, len(export.Repos))
+	for _, entry := range export.Repos {
+		var repo RepoWithLanguages
+		repo.NameWithOwner = entry.NameWithOwner
+		repo.IsFork = entry.IsFork
+		repo.Stargazers.TotalCount = entry.Stars
+		repo.ForkCount = entry.Forks
+		repo.PrimaryLanguage.Name = entry.Language
+		repo.PrimaryLanguage.Color = entry.Colour
+		for _, lang := range entry.Languages {
+			var edge LanguageEdge
+			edge.Size = lang.Size
+			edge.Node.Name = lang.Name
+			edge.Node.Color = lang.Colour
+			repo.Languages.Edges = append(repo.Languages.Edges, edge)
+		}
+
+		self._repos[entry.NameWithOwner] = repo
+		self._repoLinesChanged[entry.NameWithOwner] = [2]int{entry.Additions, entry.Deletions}
+		self._repoViews[entry.NameWithOwner] = entry.Views
+	}
+
+	self._languages = make(map[string]*helpers.LangInfo, len(export.Languages))
+	for _, lang := range export.Languages {
+		self._languages[lang.Name] = &helpers.LangInfo{
+			Size:        lang.Size,
+			Occurrences: lang.Occurrences,
+			Colour:      lang.Colour,
+			Prop:        lang.Percent,
+		}
+	}
+
+	if export.Organization != nil {
+		org := *export.Organization
+		self.organization = org.Login
+		self._organization = &org
+
+		if unavailable("contributors") {
+			self._contributorsErr = degradedError("contributors", errNotSaved)
+		} else {
+			self._contributors = slices.Clone(export.Contributors)
+			if self._contributors == nil {
+				self._contributors = []ContributorCount{}
+			}
+		}
+	}
+
+	// Contributions, activity and streaks all come from the contribution calendar, so they fail together
+	if export.Organization != nil {
+		self._contributionsErr = degradedError("contributions", errOrganization)
+	} else if unavailable("contributions") {
+		self._contributionsErr = degradedError("contributions", errNotSaved)
+	} else {
+		contributions := export.Totals.Contributions
+		activity := Activity{
+			PullRequests:       export.Totals.PullRequests,
+			MergedPullRequests: export.Totals.MergedPullRequests,
+			Issues:             export.Totals.Issues,
+			ClosedIssues:       export.Totals.ClosedIssues,
+			Reviews:            export.Totals.Reviews,
+		}
+		streaks := export.Streaks
+		self._totalContributions = &contributions
+		self._activity = &activity
+		self._streaks = &streaks
+		self._contributionDays = slices.Clone(export.Calendar)
+		if self._contributionDays == nil {
+			self._contributionDays = []ContributionDay{}
+		}
+	}
+
+	if unavailable("linesChanged") {
+		self._linesChangedErr = degradedError("lines changed", errNotSaved)
+		self._repoLinesChanged = nil
+	} else {
+		self._linesChanged = &[2]int{export.Totals.Additions, export.Totals.Deletions}
+	}
+
+	if unavailable("views") {
+		self._viewsErr = degradedError("views", errNotSaved)
+		self._repoViews = nil
+	} else {
+		views := export.Totals.Views
+		self._views = &views
+	}
+
+	if export.Totals.ProfileViews != nil {
+		profileViews := *export.Totals.ProfileViews
+		self._profileViews = &profileViews
+	} else {
+		self._profileViewsErr = degradedError("profile views", errNotSaved)
+	}
+
+	return self
+}
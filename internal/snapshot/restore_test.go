@@ -0,0 +1,88 @@
+package snapshot
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"snapshot/internal/githubtest"
+)
+
+func TestSaveLoad(t *testing.T) {
+	server := newTestServer(t)
+	server.ProfileViews = 42
+	server.MergedPullRequests = 3
+	server.Years[2024] = githubtest.Year{
+		PullRequests: 4,
+		Days: []githubtest.Day{
+			{Date: "2024-03-01", Count: 2, Level: "FIRST_QUARTILE"},
+			{Date: "2024-03-02", Count: 0, Level: "NONE"},
+			{Date: "2024-03-03", Count: 5, Level: "FOURTH_QUARTILE"},
+		},
+	}
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+
+	s := newTestSnapshot(server, Options{IncludeProfileViews: true})
+	saved, err := Save(&s, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	restored, loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.GeneratedAt.Equal(saved.GeneratedAt) {
+		t.Errorf("generatedAt = %s, want %s", loaded.GeneratedAt, saved.GeneratedAt)
+	}
+
+	// Exporting the restored snapshot again must not need the network and must give the same data
+	again, err := NewExport(&restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again.GeneratedAt = saved.GeneratedAt
+	if !reflect.DeepEqual(again, saved) {
+		t.Errorf("restored export differs from the saved one:\n%+v\n%+v", again, saved)
+	}
+
+	// Days without contributions are implied by the gaps in the saved calendar
+	days, err := GetContributionCalendar(&restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 2 || days[1].Date != "2024-03-03" || days[1].Level != "FOURTH_QUARTILE" {
+		t.Errorf("calendar = %+v, want the 2 days with contributions", days)
+	}
+}
+
+func TestRestoreUnavailable(t *testing.T) {
+	export := Export{
+		SchemaVersion: ExportSchemaVersion,
+		User:          "octocat",
+		Name:          "The Octocat",
+		Unavailable:   []string{"contributions", "pullRequests", "linesChanged", "views", "profileViews"},
+	}
+	s := Restore(export)
+
+	if !s.IncludeProfileViews {
+		t.Error("profile views were requested when the snapshot was saved")
+	}
+	for name, get := range map[string]func() error{
+		"contributions": func() error { _, err := GetContributions(&s); return err },
+		"activity":      func() error { _, err := GetActivity(&s); return err },
+		"calendar":      func() error { _, err := GetContributionCalendar(&s); return err },
+		"linesChanged":  func() error { _, err := GetLinesChanged(&s); return err },
+		"views":         func() error { _, err := GetViews(&s); return err },
+		"profileViews":  func() error { _, err := GetProfileViews(&s); return err },
+	} {
+		if err := get(); err == nil || IsFatal(err) {
+			t.Errorf("%s error = %v, want a degraded error", name, err)
+		}
+	}
+
+	if name, err := GetName(&s); err != nil || name != "The Octocat" {
+		t.Errorf("name = %q, %v", name, err)
+	}
+}
@@ -3,126 +3,215 @@ package snapshot
 import (
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"snapshot/internal/helpers"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hasura/go-graphql-client"
 )
 
-func NewSnapshot(user string, accessToken string, excludedRepos map[string]struct{}, excludedLangs map[string]struct{}, includeForkedRepos bool, includeExternalRepos bool, includeProfileViews bool) Snapshot {
-	client := &http.Client{Transport: &helpers.TransportWithToken{
-		Token:     accessToken,
-		Transport: http.DefaultTransport,
-	}}
+// Options configures a Snapshot.
+type Options struct {
+	User                 string              // Login of the user the snapshot is generated for
+	Organization         string              // Login of the organization the snapshot is generated for instead of the user, if set
+	Public               bool                // Only query the public data of User, so the token does not have to belong to them
+	AccessToken          string              // Token sent with every GitHub API request
+	Endpoints            helpers.Endpoints   // Base URLs of the GitHub APIs and the profile views counter
+	ExcludedRepos        map[string]struct{} // Lower case nameWithOwner of repos left out of every metric
+	ExcludedLangs        map[string]struct{} // Lower case names of languages left out of the languages metric
+	IncludeForkedRepos   bool
+	IncludeExternalRepos bool
+	IncludeProfileViews  bool
+	Workers              int                  // Maximum number of repos fetched at once
+	Limiter              *helpers.RateLimiter // Shared API budget, a new one is created if nil
+	RetryPolicy          helpers.RetryPolicy  // Applied to every request, DefaultRetryPolicy if unset
+	Transport            http.RoundTripper    // Sends the requests, http.DefaultTransport if nil
+	Client               *http.Client         // Shared client built by NewClient, overrides AccessToken, Limiter, RetryPolicy and Transport if set, Transport still downloads the avatars
+}
 
-	queryClient := graphql.NewClient("https://api.github.com/graphql", http.DefaultClient).
-		WithRequestModifier(func(r *http.Request) {
-			r.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
-		})
+func NewSnapshot(opts Options) Snapshot {
+	client := opts.Client
+	if client == nil {
+		client = NewClient(opts)
+	}
+
+	queryClient := graphql.NewClient(opts.Endpoints.GraphQL, client)
 
 	return Snapshot{
-		user:                 user,
-		accessToken:          accessToken,
+		user:                 opts.User,
+		organization:         opts.Organization,
+		public:               opts.Public,
+		accessToken:          opts.AccessToken,
+		endpoints:            opts.Endpoints,
 		client:               client,
 		queryClient:          queryClient,
-		excludedRepos:        excludedRepos,
-		excludedLangs:        excludedLangs,
-		includeForkedRepos:   includeForkedRepos,
-		includeExternalRepos: includeExternalRepos,
-		IncludeProfileViews:  includeProfileViews,
+		plainClient:          &http.Client{Transport: opts.Transport}, // Avatars and the profile views counter are served by other hosts, which must not receive the token
+		excludedRepos:        opts.ExcludedRepos,
+		excludedLangs:        opts.ExcludedLangs,
+		includeForkedRepos:   opts.IncludeForkedRepos,
+		includeExternalRepos: opts.IncludeExternalRepos,
+		IncludeProfileViews:  opts.IncludeProfileViews && opts.Organization == "", // Profile views only exist for users
+		workers:              opts.Workers,
 		_name:                nil,
 		_stargazers:          nil,
 		_forks:               nil,
 		_totalContributions:  nil,
+		_contributionDays:    nil,
+		_streaks:             nil,
+		_activity:            nil,
 		_languages:           nil,
 		_repos:               nil,
 		_linesChanged:        nil,
 		_views:               nil,
+		_repoLinesChanged:    nil,
+		_repoViews:           nil,
 		_profileViews:        nil,
 	}
 }
 
-func getViewerName(q *ReposOverviewQuery) *string {
-	if q.Viewer.Name != "" {
-		return &q.Viewer.Name
+// NewClient builds the HTTP client a snapshot sends its requests with.
+// Every request, typed graphql queries included, is retried by the same policy and passes through the shared rate limiter.
+// A client can be shared by several snapshots generated with the same token, so they also share the API budget.
+func NewClient(opts Options) *http.Client {
+	limiter := opts.Limiter
+	if limiter == nil {
+		limiter = helpers.NewRateLimiter()
+	}
+	retryPolicy := opts.RetryPolicy
+	if retryPolicy.MaxAttempts == 0 {
+		retryPolicy = helpers.DefaultRetryPolicy()
+	}
+	transport := opts.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &http.Client{Transport: &helpers.TransportWithToken{
+		Token: opts.AccessToken,
+		Transport: &helpers.RetryTransport{
+			Policy: retryPolicy,
+			Transport: &helpers.RateLimitTransport{
+				Limiter:   limiter,
+				Transport: transport,
+			},
+		},
+	}}
+
+}
+
+// errPublic is reported for the traffic views of public snapshots, which need push access to every repo.
+var errPublic = errors.New("not available for public snapshots")
+
+func getViewerName(name string, login string) *string {
+	if name != "" {
+		return &name
 	}
-	if q.Viewer.Login != "" {
-		return &q.Viewer.Login
+	if login != "" {
+		return &login
 	}
-	name := "No Name"
+	name = "No Name"
 	return &name
 }
 
 // GetStats collects the user's Github statistics based on the repos they own or have contributed to.
 // It fills out the Snapshot object's attributes for later use.
-func getStats(self *Snapshot) {
-	if self._stargazers == nil {
-		tmp := 0
-		self._stargazers = &tmp
-	}
-	if self._forks == nil {
-		tmp := 0
-		self._forks = &tmp
+// A failure is remembered, so every getter depending on these stats reports it without querying again.
+func getStats(self *Snapshot) error {
+	if self._statsErr != nil {
+		return self._statsErr
 	}
+
+	stargazers := 0
+	forks := 0
+	self._stargazers = &stargazers
+	self._forks = &forks
 	self._repos = make(map[string]RepoWithLanguages)
+	self._languages = make(map[string]*helpers.LangInfo)
+
+	if self.organization != "" {
+		if err := getOrgStats(self); err != nil {
+			// Drop the partially collected stats so no getter returns them
+			self._name, self._stargazers, self._forks, self._repos, self._languages, self._organization = nil, nil, nil, nil, nil, nil
+			self._statsErr = fatalError("repositories", err)
+			return self._statsErr
+		}
+		computeLanguageProportions(self)
+		return nil
+	}
 
 	repoCursor := graphql.String("")
 	contribCursor := graphql.String("")
-	// runKey := rand.Int()
 	for {
-		statsQuery, cursors := reposOverview(helpers.StringPtrOrNil(repoCursor), helpers.StringPtrOrNil(contribCursor))
-		helpers.RunQuery(self.queryClient, statsQuery, cursors)
+		user, err := runReposOverview(self, repoCursor, contribCursor)
+		if err != nil {
+			// Drop the partially collected stats so no getter returns them
+			self._name, self._stargazers, self._forks, self._repos, self._languages = nil, nil, nil, nil, nil
+			self._statsErr = fatalError("repositories", err)
+			return self._statsErr
+		}
 
-		self._name = getViewerName(statsQuery)
-		repos := statsQuery.Viewer.Repositories.Nodes
+		self._name = getViewerName(user.Name, user.Login)
+		self._avatarUrl = user.AvatarUrl
+		repos := user.Repositories.Nodes
 
 		// Include repos contributed to without access rights if IncludeExternalRepos is set to true (default is false)
 		if self.includeExternalRepos {
-			repos = append(repos, statsQuery.Viewer.RepositoriesContributedTo.Nodes...)
+			repos = append(repos, user.RepositoriesContributedTo.Nodes...)
 		}
 
 		for _, repo := range repos {
-
-			// Ignore excluded repos
-			_, excluded := self.excludedRepos[repo.NameWithOwner]
-			if excluded {
-				continue
-			}
-
-			// Ignore duplicate repos from RepositoriesContributedTo if already seen in Repositories or the the other way around
-			_, seen := self._repos[repo.NameWithOwner]
-			if seen {
-				continue
-			}
-
-			// Dont count stats if the repo is not a fork of another one or includeForkedRepos is set to false (default)
-			if repo.IsFork && !self.includeForkedRepos {
-				continue
-			}
-
-			self._repos[repo.NameWithOwner] = repo
-			parseRepoLanguages(self, &repo)
-
-			if repo.Stargazers.TotalCount > 0 {
-				*self._stargazers += repo.Stargazers.TotalCount
-				// log.Printf("Get Stats[%d] -> Added %d Stargazers from %s\n", runKey, repo.Stargazers.TotalCount, repo.NameWithOwner)
-			}
-			*self._forks += repo.ForkCount
+			countRepo(self, repo)
 		}
 		// Update cursors
-		repoCursor = graphql.String(statsQuery.Viewer.Repositories.PageInfo.EndCursor)
-		contribCursor = graphql.String(statsQuery.Viewer.RepositoriesContributedTo.PageInfo.EndCursor)
+		repoCursor = graphql.String(user.Repositories.PageInfo.EndCursor)
+		contribCursor = graphql.String(user.RepositoriesContributedTo.PageInfo.EndCursor)
 
 		// Exit if no more pages
-		if !statsQuery.Viewer.Repositories.PageInfo.HasNextPage && !statsQuery.Viewer.RepositoriesContributedTo.PageInfo.HasNextPage {
+		if !user.Repositories.PageInfo.HasNextPage && !user.RepositoriesContributedTo.PageInfo.HasNextPage {
 			break
 		}
 	}
 
+	computeLanguageProportions(self)
+	return nil
+}
+
+// countRepo adds a repo to the stats unless it is excluded, already counted or an unwanted fork.
+func countRepo(self *Snapshot, repo RepoWithLanguages) {
+	// Ignore excluded repos
+	_, excluded := self.excludedRepos[strings.ToLower(repo.NameWithOwner)]
+	if excluded {
+		return
+	}
+
+	// Ignore duplicate repos from RepositoriesContributedTo if already seen in Repositories or the the other way around
+	_, seen := self._repos[repo.NameWithOwner]
+	if seen {
+		return
+	}
+
+	// Dont count stats if the repo is not a fork of another one or includeForkedRepos is set to false (default)
+	if repo.IsFork && !self.includeForkedRepos {
+		return
+	}
+
+	self._repos[repo.NameWithOwner] = repo
+	parseRepoLanguages(self, &repo)
+
+	if repo.Stargazers.TotalCount > 0 {
+		*self._stargazers += repo.Stargazers.TotalCount
+	}
+	*self._forks += repo.ForkCount
+}
+
+// computeLanguageProportions sets the share of every language in the total size of all counted languages.
+func computeLanguageProportions(self *Snapshot) {
 	// # TODO: Improve languages to scale by number of contributions to
 	// #       specific filetypes
 	total := 0
@@ -137,11 +226,6 @@ func getStats(self *Snapshot) {
 }
 
 func parseRepoLanguages(self *Snapshot, repo *RepoWithLanguages) {
-	// Initialise languages
-	if self._languages == nil {
-		self._languages = make(map[string]*helpers.LangInfo)
-	}
-
 	for _, langEdge := range repo.Languages.Edges {
 
 		// Check if language should be excluded
@@ -172,6 +256,36 @@ func parseRepoLanguages(self *Snapshot, repo *RepoWithLanguages) {
 	}
 }
 
+// userRepos is a page of the user's repositories, whether they were queried through viewer or user(login:).
+type userRepos struct {
+	Login                     string
+	Name                      string
+	AvatarUrl                 string
+	Repositories              RepoConnection
+	RepositoriesContributedTo RepoConnection
+}
+
+// runReposOverview queries a page of the user's repositories, only the public ones in public mode.
+func runReposOverview(self *Snapshot, repoCursor, contribCursor graphql.String) (userRepos, error) {
+	statsQuery, vars := reposOverview(helpers.StringPtrOrNil(repoCursor), helpers.StringPtrOrNil(contribCursor))
+	if !self.public {
+		if err := helpers.RunQuery(self.queryClient, statsQuery, vars); err != nil {
+			return userRepos{}, err
+		}
+		return userRepos(statsQuery.Viewer), nil
+	}
+
+	var publicQuery PublicReposOverviewQuery
+	vars["login"] = graphql.String(self.user)
+	if err := helpers.RunQuery(self.queryClient, &publicQuery, vars); err != nil {
+		return userRepos{}, err
+	}
+	if publicQuery.User.Login == "" {
+		return userRepos{}, fmt.Errorf("user %s not found", self.user)
+	}
+	return userRepos(publicQuery.User), nil
+}
+
 func reposOverview(ownedCursor, contribCursor *string) (*ReposOverviewQuery, map[string]any) {
 	query := &ReposOverviewQuery{}
 
@@ -199,144 +313,362 @@ func contribsByYearQuery(year int) string {
         from: "%d-01-01T00:00:00Z",
         to: "%d-01-01T00:00:00Z"
     ) {
+      totalPullRequestContributions
+      totalIssueContributions
+      totalPullRequestReviewContributions
       contributionCalendar {
         totalContributions
+        weeks {
+          contributionDays {
+            date
+            contributionCount
+            contributionLevel
+          }
+        }
       }
     }`, year, year, year+1)
 }
 
+// ActivitySearchQuery builds graphql search queries counting the user's merged pull requests and closed issues.
+// These are not part of contributionsCollection, so they are counted across all time with the search API instead.
+// Returns the built query fragment as a string.
+func activitySearchQuery(login string) string {
+	return fmt.Sprintf(`
+  mergedPullRequests: search(query: "author:%s is:pr is:merged", type: ISSUE) {
+    issueCount
+  }
+  closedIssues: search(query: "author:%s is:issue is:closed", type: ISSUE) {
+    issueCount
+  }`, login, login)
+}
+
 // AllContributionsQuery dynamically builds a graphql query to get all the contribution counts for a given list of years.
+// It also includes the search counts for the user's merged pull requests and closed issues.
+// Public queries go through user(login:), aliased as viewer so both are read the same way.
 // Returns the built query as a string.
-func allContributionsQuery(years []int) string {
+func allContributionsQuery(login string, years []int, public bool) string {
 	fragments := make([]string, len(years))
 	for i, year := range years {
 		fragments[i] = contribsByYearQuery(year)
 	}
 
-	return fmt.Sprintf("query {\n  viewer {\n%s\n  }\n}", strings.Join(fragments, "\n"))
+	root := "viewer"
+	if public {
+		root = fmt.Sprintf("viewer: user(login: %q)", login)
+	}
+
+	return fmt.Sprintf("query {\n  %s {\n%s\n  }\n%s\n}", root, strings.Join(fragments, "\n"), activitySearchQuery(login))
 }
 
 // Properties
-func GetName(self *Snapshot) string {
+func GetName(self *Snapshot) (string, error) {
 	if self._name != nil {
-		return *self._name
+		return *self._name, nil
+	}
+
+	if err := getStats(self); err != nil {
+		return "", err
+	}
+	return *self._name, nil
+}
+
+// GetLogin returns the login the snapshot is generated for, the organization's in organization mode.
+func GetLogin(self *Snapshot) string {
+	if self.organization != "" {
+		return self.organization
+	}
+	return self.user
+}
+
+// GetAvatar returns the avatar of the user or organization as a data URI.
+// A user's avatar is only downloaded the first time it is asked for. It is not a metric, so a failed download only leaves it empty.
+func GetAvatar(self *Snapshot) (string, error) {
+	if self.organization != "" {
+		org, err := GetOrganization(self)
+		return org.Avatar, err
+	}
+	if self._avatar != nil {
+		return *self._avatar, nil
+	}
+
+	if _, err := GetName(self); err != nil {
+		return "", err
 	}
 
-	getStats(self)
-	return *self._name
+	var avatar string
+	if self._avatarUrl != "" {
+		var err error
+		if avatar, err = helpers.FetchDataURI(self.plainClient, self._avatarUrl); err != nil {
+			log.Printf("Failed to get the avatar of %s: %v", self.user, err)
+		}
+	}
+	self._avatar = &avatar
+	return avatar, nil
 }
 
-func GetStargazers(self *Snapshot) int {
+func GetStargazers(self *Snapshot) (int, error) {
 	if self._stargazers != nil {
-		return *self._stargazers
+		return *self._stargazers, nil
 	}
 
-	getStats(self)
-	return *self._stargazers
+	if err := getStats(self); err != nil {
+		return 0, err
+	}
+	return *self._stargazers, nil
 }
 
-func GetForks(self *Snapshot) int {
+func GetForks(self *Snapshot) (int, error) {
 	if self._forks != nil {
-		return *self._forks
+		return *self._forks, nil
 	}
 
-	getStats(self)
-	return *self._forks
+	if err := getStats(self); err != nil {
+		return 0, err
+	}
+	return *self._forks, nil
 }
 
-func GetViews(self *Snapshot) int {
+// GetViews returns the views of every counted repo over the past two weeks.
+// Repos whose views cannot be read (e.g. without push access) are left out rather than failing the metric.
+// Public snapshots have no push access to rely on, so the metric is unavailable for them.
+func GetViews(self *Snapshot) (int, error) {
 	if self._views != nil {
-		return *self._views
+		return *self._views, nil
+	}
+	if self._viewsErr != nil {
+		return 0, self._viewsErr
+	}
+	if self.public {
+		self._viewsErr = degradedError("views", errPublic)
+		return 0, self._viewsErr
 	}
 
-	total := 0
+	counted, err := GetRepos(self)
+	if err != nil {
+		return 0, err
+	}
 
-	for repo := range self._repos {
-		uri := fmt.Sprintf("https://api.github.com/repos/%s/traffic/views", repo)
+	repos := make([]string, 0, len(counted))
+	for repo := range counted {
+		repos = append(repos, repo)
+	}
 
-		response, err := self.client.Get(uri)
+	// Fetch every repo's views concurrently, then aggregate the results on this goroutine
+	views := helpers.RunPool(self.workers, repos, func(repo string) *int {
+		return getRepoViews(self, repo)
+	})
 
-		if err != nil {
+	total := 0
+	self._repoViews = make(map[string]int)
+	for i, count := range views {
+		if count == nil {
 			continue
 		}
+		total += *count
+		self._repoViews[repos[i]] = *count
+	}
 
-		defer response.Body.Close()
+	self._views = &total
+	return total, nil
+}
 
-		var res struct {
-			Count float64 `json:"count"`
-		}
+// getRepoViews fetches the views of a single repo over the past two weeks.
+// It returns nil if the views could not be retrieved, e.g. without push access to the repo.
+func getRepoViews(self *Snapshot, repo string) *int {
+	uri := fmt.Sprintf("repos/%s/traffic/views", repo)
 
-		if err := json.NewDecoder(response.Body).Decode(&res); err != nil {
-			log.Printf("Failed to decode: %v", err)
-			continue
-		}
+	response, err := helpers.RunRestQuery(self.client, self.endpoints.REST, uri, nil)
 
-		total += int(res.Count)
+	if err != nil {
+		log.Printf("Failed to get views of %s: %v", repo, err)
+		return nil
+	}
 
+	var res struct {
+		Count float64 `json:"count"`
 	}
 
-	self._views = &total
-	return total
+	if err := json.Unmarshal(response, &res); err != nil {
+		log.Printf("Failed to decode: %v", err)
+		return nil
+	}
+
+	count := int(res.Count)
+	return &count
 }
 
-func GetRepos(self *Snapshot) map[string]RepoWithLanguages {
+func GetRepos(self *Snapshot) (map[string]RepoWithLanguages, error) {
 	if self._repos != nil {
-		return self._repos
+		return self._repos, nil
 	}
-	getStats(self)
-	return self._repos
+
+	if err := getStats(self); err != nil {
+		return nil, err
+	}
+	return self._repos, nil
 }
 
-func GetContributions(self *Snapshot) int {
+func GetContributions(self *Snapshot) (int, error) {
 	if self._totalContributions != nil {
-		return *self._totalContributions
+		return *self._totalContributions, nil
 	}
 
-	tmp := 0
-	self._totalContributions = &tmp
+	if err := getContributionCalendar(self); err != nil {
+		return 0, err
+	}
+	return *self._totalContributions, nil
+}
 
-	var yearsQuery ContributionYearsQuery
+// GetContributionCalendar returns the number of contributions made on every day of every contribution year, sorted by date.
+func GetContributionCalendar(self *Snapshot) ([]ContributionDay, error) {
+	if self._contributionDays != nil {
+		return self._contributionDays, nil
+	}
 
-	err := helpers.RunQuery(self.queryClient, &yearsQuery, nil)
+	if err := getContributionCalendar(self); err != nil {
+		return nil, err
+	}
+	return self._contributionDays, nil
+}
+
+// getContributionCalendar queries the contribution calendar of every year the user has contributed in.
+// It fills out the total contribution count, the per-day contributions and the activity counts of the Snapshot.
+// A failure is remembered, so every getter depending on the calendar reports it without querying again.
+func getContributionCalendar(self *Snapshot) error {
+	if self._contributionsErr != nil {
+		return self._contributionsErr
+	}
+	if self.organization != "" {
+		self._contributionsErr = degradedError("contributions", errOrganization)
+		return self._contributionsErr
+	}
+
+	years, err := getContributionYears(self)
 	if err != nil {
-		log.Fatalf("Failed to get contribution years: %s", err)
+		self._contributionsErr = degradedError("contribution years", err)
+		return self._contributionsErr
 	}
-	years := yearsQuery.Viewer.ContributionsCollection.ContributionYears
 
 	var result map[string]any
 
-	query := allContributionsQuery(years)
+	query := allContributionsQuery(self.user, years, self.public)
+
+	result, err = helpers.RunRawQuery(self.client, self.endpoints.GraphQL, query)
+	if err != nil {
+		self._contributionsErr = degradedError("contributions", err)
+		return self._contributionsErr
+	}
 
-	result, err = helpers.RunRawQuery(self.client, query)
+	// Re-encode the raw viewer object so each aliased year can be decoded into a typed struct
+	raw, err := json.Marshal(result["viewer"])
 	if err != nil {
-		log.Fatalf("Raw Query Failed: %s", err)
+		self._contributionsErr = degradedError("contributions", err)
+		return self._contributionsErr
+	}
+
+	var viewer map[string]ContributionYear
+	if err := json.Unmarshal(raw, &viewer); err != nil {
+		self._contributionsErr = degradedError("contributions", fmt.Errorf("failed to decode contribution calendar: %w", err))
+		return self._contributionsErr
 	}
 
-	viewer := result["viewer"].(map[string]any)
 	total := 0
+	activity := Activity{
+		MergedPullRequests: searchCount(result, "mergedPullRequests"),
+		ClosedIssues:       searchCount(result, "closedIssues"),
+	}
+	byDate := make(map[string]ContributionDay)
 	for year, v := range viewer {
-		contribCollection := v.(map[string]any)
-		calendar := contribCollection["contributionCalendar"].(map[string]any)
-		contributions := int(calendar["totalContributions"].(float64))
+		contributions := v.ContributionCalendar.TotalContributions
 		total += contributions
 		log.Printf("Made %d contributions in [%s]", contributions, year)
+
+		activity.PullRequests += v.TotalPullRequestContributions
+		activity.Issues += v.TotalIssueContributions
+		activity.Reviews += v.TotalPullRequestReviewContributions
+
+		// Adjacent year ranges share their boundary instant, so a day may be returned by both years
+		for _, week := range v.ContributionCalendar.Weeks {
+			for _, day := range week.ContributionDays {
+				byDate[day.Date] = day
+			}
+		}
+	}
+
+	days := make([]ContributionDay, 0, len(byDate))
+	for _, day := range byDate {
+		days = append(days, day)
 	}
-	return total
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Date < days[j].Date
+	})
+
+	// The total is only cached once the calendar is complete, so no getter can return a partial or placeholder count
+	self._totalContributions = &total
+	self._contributionDays = days
+	self._activity = &activity
+	return nil
 }
 
-func GetLinesChanged(self *Snapshot) int64 {
+// getContributionYears returns the years the user has contributed in, most recent first.
+func getContributionYears(self *Snapshot) ([]int, error) {
+	if self.public {
+		var yearsQuery PublicContributionYearsQuery
+		err := helpers.RunQuery(self.queryClient, &yearsQuery, map[string]any{"login": graphql.String(self.user)})
+		return yearsQuery.User.ContributionsCollection.ContributionYears, err
+	}
+
+	var yearsQuery ContributionYearsQuery
+	err := helpers.RunQuery(self.queryClient, &yearsQuery, nil)
+	return yearsQuery.Viewer.ContributionsCollection.ContributionYears, err
+}
+
+// searchCount reads the issueCount of an aliased search from a raw graphql result, returning 0 if it is missing.
+func searchCount(result map[string]any, alias string) int {
+	search, ok := result[alias].(map[string]any)
+	if !ok {
+		return 0
+	}
+	count, _ := search["issueCount"].(float64)
+	return int(count)
+}
+
+// GetActivity returns the pull requests, issues and reviews the user has opened, merged, closed or given across all years.
+func GetActivity(self *Snapshot) (Activity, error) {
+	if self._activity != nil {
+		return *self._activity, nil
+	}
+
+	if err := getContributionCalendar(self); err != nil {
+		return Activity{}, err
+	}
+	return *self._activity, nil
+}
+
+// GetLinesChanged returns the lines added plus deleted by the user across every counted repo.
+// If the history of any repo cannot be read, the metric fails as a whole instead of reporting a partial count.
+func GetLinesChanged(self *Snapshot) (int64, error) {
 	if self._linesChanged != nil {
-		return int64(self._linesChanged[0] + self._linesChanged[1])
+		return int64(self._linesChanged[0] + self._linesChanged[1]), nil
+	}
+	if self._linesChangedErr != nil {
+		return 0, self._linesChangedErr
+	}
+
+	counted, err := GetRepos(self)
+	if err != nil {
+		return 0, err
 	}
 
 	additions := 0
 	deletions := 0
+	repoLinesChanged := make(map[string][2]int)
 
 	// Get lines changed via REST API (far slower, around 10 seconds per repo, results in slightly different count)
 	// for repo := range self._repos {
 	// 	uri := fmt.Sprintf("repos/%s/stats/contributors", repo)
 
-	// 	response, err := helpers.RunRestQuery(self.client, uri, nil)
+	// 	response, err := helpers.RunRestQuery(self.client, self.endpoints.REST, uri, nil)
 
 	// 	if err != nil {
 	// 		log.Printf("Failed to fetch %s: %v", uri, err)
@@ -364,73 +696,205 @@ func GetLinesChanged(self *Snapshot) int64 {
 
 	// }
 
-	for _, repo := range self._repos {
+	repos := make([]RepoWithLanguages, 0, len(counted))
+	for _, repo := range counted {
+		if _, excluded := self.excludedRepos[strings.ToLower(repo.NameWithOwner)]; excluded {
+			continue
+		}
+		repos = append(repos, repo)
+	}
 
-		_, excluded := self.excludedRepos[repo.NameWithOwner]
-		if excluded {
+	// Walk every repo's history concurrently, then aggregate the results on this goroutine
+	type repoResult struct {
+		lines *[2]int
+		err   error
+	}
+	results := helpers.RunPool(self.workers, repos, func(repo RepoWithLanguages) repoResult {
+		lines, err := getRepoLinesChanged(self, repo)
+		return repoResult{lines, err}
+	})
+
+	var errs []error
+	for i, result := range results {
+		if result.err != nil {
+			errs = append(errs, result.err)
 			continue
 		}
+		if result.lines == nil {
+			continue
+		}
+		additions += result.lines[0]
+		deletions += result.lines[1]
+		repoLinesChanged[repos[i].NameWithOwner] = *result.lines
+	}
 
-		owner, name, err := helpers.SplitOwnerRepo(repo.NameWithOwner)
+	if len(errs) > 0 {
+		self._linesChangedErr = degradedError("lines changed", errors.Join(errs...))
+		return 0, self._linesChangedErr
+	}
 
-		if err != nil {
-			// No owner and repo split was found
-			continue
+	self._repoLinesChanged = repoLinesChanged
+	self._linesChanged = &[2]int{additions, deletions} // [0]=add, [1]=del
+	return int64(self._linesChanged[0] + self._linesChanged[1]), nil
+}
+
+// getRepoLinesChanged walks the default branch history of a single repo, newest commit first.
+// If the repo is in the lines cache, the walk stops at the last counted commit and the new lines are added to the cached totals.
+// If that commit is no longer part of the history (e.g. after a force-push), the walk reaches the end and the fresh full count is used instead.
+// New merge commits also fall back to a full count, as the merged commits can be listed after the last counted one.
+// It returns the lines added and deleted by the user, or by every author in organization mode, or nil if the repo name could not be split.
+// The cursor of a repo is only updated once its walk succeeded.
+func getRepoLinesChanged(self *Snapshot, repo RepoWithLanguages) (*[2]int, error) {
+	owner, name, err := helpers.SplitOwnerRepo(repo.NameWithOwner)
+
+	if err != nil {
+		// No owner and repo split was found
+		return nil, nil
+	}
+
+	var cached RepoCursor
+	var isCached bool
+	if self.linesCache != nil {
+		cached, isCached = self.linesCache.Get(repo.NameWithOwner)
+	}
+
+	walk, err := walkHistory(self, owner, name, cached.HeadOID)
+	if err != nil {
+		return nil, fmt.Errorf("commit history of %s: %w", repo.NameWithOwner, err)
+	}
+
+	switch {
+	case walk.reachedStop && walk.merged:
+		// The history is listed by commit date, so the commits of a merged branch that are older than the cached head
+		// are listed after it. Only a full walk is sure to count them.
+		log.Printf("A branch was merged into %s since the last run, counting it from scratch", repo.NameWithOwner)
+		if walk, err = walkHistory(self, owner, name, ""); err != nil {
+			return nil, fmt.Errorf("commit history of %s: %w", repo.NameWithOwner, err)
 		}
+	case walk.reachedStop:
+		walk.additions += cached.Additions
+		walk.deletions += cached.Deletions
+	case isCached && cached.HeadOID != "":
+		log.Printf("History of %s was rewritten since the last run, counted it from scratch", repo.NameWithOwner)
+	}
 
-		var cursor *graphql.String = nil
-		page := 1
-
-		// log.Printf("Getting total commit info for %s", repo.NameWithOwner)
-		for {
-			// log.Printf("Page: %d", page)
-			var commitQuery CommitStatsQuery
-			vars := map[string]any{
-				"owner":        graphql.String(owner),
-				"name":         graphql.String(name),
-				"commitCursor": cursor,
-			}
+	if self.linesCache != nil {
+		self.linesCache.Put(repo.NameWithOwner, RepoCursor{
+			HeadOID:   walk.headOID,
+			Additions: walk.additions,
+			Deletions: walk.deletions,
+			UpdatedAt: time.Now().UTC(),
+		})
+	}
 
-			helpers.RunQuery(self.queryClient, &commitQuery, vars)
+	return &[2]int{walk.additions, walk.deletions}, nil
+}
 
-			history := commitQuery.Repository.DefaultBranchRef.Target.Commit.History
-			for _, commit := range history.Nodes {
-				if commit.Author.User.Login == self.user {
-					additions += commit.Additions
-					deletions += commit.Deletions
-				}
-			}
+// historyWalk is the result of walkHistory.
+type historyWalk struct {
+	additions   int
+	deletions   int
+	headOID     string
+	reachedStop bool // The walk stopped at the stop commit instead of the end of the history
+	merged      bool // A merge commit was walked before the stop commit
+}
 
-			cursor = &history.PageInfo.EndCursor
+// walkHistory counts the lines changed in the default branch history of owner/name, newest commit first,
+// up to but not including the commit stopAt. An empty stopAt walks the whole history.
+func walkHistory(self *Snapshot, owner string, name string, stopAt string) (historyWalk, error) {
+	var walk historyWalk
+	var cursor *graphql.String = nil
+	page := 1
+
+	// log.Printf("Getting total commit info for %s/%s", owner, name)
+	for !walk.reachedStop {
+		// log.Printf("Page: %d", page)
+		var commitQuery CommitStatsQuery
+		vars := map[string]any{
+			"owner":        graphql.String(owner),
+			"name":         graphql.String(name),
+			"commitCursor": cursor,
+		}
+
+		if err := helpers.RunQuery(self.queryClient, &commitQuery, vars); err != nil {
+			return historyWalk{}, err
+		}
 
-			if !history.PageInfo.HasNextPage {
+		history := commitQuery.Repository.DefaultBranchRef.Target.Commit.History
+		for _, commit := range history.Nodes {
+			if walk.headOID == "" {
+				walk.headOID = commit.Oid
+			}
+
+			if stopAt != "" && commit.Oid == stopAt {
+				walk.reachedStop = true
 				break
 			}
+			walk.merged = walk.merged || commit.Parents.TotalCount > 1
+
+			// Organization snapshots count the lines of every author
+			if self.organization != "" || commit.Author.User.Login == self.user {
+				walk.additions += commit.Additions
+				walk.deletions += commit.Deletions
+			}
+		}
+
+		cursor = &history.PageInfo.EndCursor
 
-			page++
+		if !history.PageInfo.HasNextPage {
+			break
 		}
+
+		page++
 	}
 
-	self._linesChanged = &[2]int{additions, deletions} // [0]=add, [1]=del
-	return int64(self._linesChanged[0] + self._linesChanged[1])
+	return walk, nil
 }
 
-func GetLanguages(self *Snapshot) map[string]*helpers.LangInfo {
+// GetRepoLinesChanged returns the lines added and deleted by the user in each counted repo, keyed by nameWithOwner.
+func GetRepoLinesChanged(self *Snapshot) (map[string][2]int, error) {
+	if self._repoLinesChanged == nil {
+		if _, err := GetLinesChanged(self); err != nil {
+			return nil, err
+		}
+	}
+	return self._repoLinesChanged, nil
+}
+
+// GetRepoViews returns the views of each counted repo over the past two weeks, keyed by nameWithOwner.
+func GetRepoViews(self *Snapshot) (map[string]int, error) {
+	if self._repoViews == nil {
+		if _, err := GetViews(self); err != nil {
+			return nil, err
+		}
+	}
+	return self._repoViews, nil
+}
+
+func GetLanguages(self *Snapshot) (map[string]*helpers.LangInfo, error) {
 	if self._languages != nil {
-		return self._languages
+		return self._languages, nil
 	}
-	getStats(self)
-	return self._languages
+
+	if err := getStats(self); err != nil {
+		return nil, err
+	}
+	return self._languages, nil
 }
 
-func GetProfileViews(self *Snapshot) int {
+// GetProfileViews reads the profile view count from the komarev.com counter badge.
+func GetProfileViews(self *Snapshot) (int, error) {
 	if self._profileViews != nil {
-		return *self._profileViews
+		return *self._profileViews, nil
+	}
+	if self._profileViewsErr != nil {
+		return 0, self._profileViewsErr
 	}
 
-	svg, err := helpers.RunSVGRestQuery(self.client, fmt.Sprintf("https://komarev.com/ghpvc/?username=%s", self.user), nil)
+	svg, err := helpers.RunSVGRestQuery(self.plainClient, self.endpoints.ProfileViews, map[string]string{"username": self.user})
 	if err != nil {
-		log.Fatal(err)
+		self._profileViewsErr = degradedError("profile views", err)
+		return 0, self._profileViewsErr
 	}
 
 	decoder := xml.NewDecoder(strings.NewReader(svg))
@@ -454,11 +918,12 @@ func GetProfileViews(self *Snapshot) int {
 				value, err := strconv.Atoi(cleaned)
 				if err == nil {
 					self._profileViews = &value
-					return value
+					return value, nil
 				}
 			}
 		}
 	}
 
-	return 0
+	self._profileViewsErr = degradedError("profile views", fmt.Errorf("no view count found in the counter badge"))
+	return 0, self._profileViewsErr
 }
@@ -0,0 +1,529 @@
+package snapshot
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"snapshot/internal/githubtest"
+	"snapshot/internal/helpers"
+)
+
+// newTestSnapshot builds a snapshot of the fake server's user that fails fast instead of retrying.
+func newTestSnapshot(server *githubtest.Server, opts Options) Snapshot {
+	opts.User = server.Login
+	opts.AccessToken = server.Token
+	opts.Endpoints = server.Endpoints()
+	opts.RetryPolicy = helpers.RetryPolicy{MaxAttempts: 1}
+	if opts.Workers == 0 {
+		opts.Workers = 4
+	}
+	return NewSnapshot(opts)
+}
+
+func newTestServer(t *testing.T) *githubtest.Server {
+	server := githubtest.NewServer(t, "octocat")
+	server.Token = "test-token"
+	server.Name = "The Octocat"
+	server.Repos = []githubtest.Repo{
+		{
+			NameWithOwner: "octocat/hello-world",
+			Stars:         10,
+			Forks:         2,
+			Languages: []githubtest.Language{
+				{Name: "Go", Color: "#00ADD8", Size: 300},
+				{Name: "HTML", Color: "#e34c26", Size: 100},
+			},
+			Commits: []githubtest.Commit{
+				{Oid: "c3", Author: "octocat", Additions: 5, Deletions: 1},
+				{Oid: "c2", Author: "someone", Additions: 100, Deletions: 100},
+				{Oid: "c1", Author: "octocat", Additions: 10, Deletions: 2},
+			},
+			Views: 7,
+		},
+		{
+			NameWithOwner: "octocat/spoon-knife",
+			Stars:         3,
+			Forks:         1,
+			Languages: []githubtest.Language{
+				{Name: "Go", Color: "#00ADD8", Size: 100},
+				{Name: "Shell", Size: 50},
+			},
+			Commits: []githubtest.Commit{
+				{Oid: "s1", Author: "octocat", Additions: 4, Deletions: 4},
+			},
+			NoTraffic: true,
+		},
+		{
+			NameWithOwner: "octocat/forked",
+			IsFork:        true,
+			Stars:         100,
+		},
+		{
+			NameWithOwner: "github/docs",
+			External:      true,
+			Stars:         1000,
+			Forks:         50,
+			Commits: []githubtest.Commit{
+				{Oid: "d1", Author: "octocat", Additions: 1, Deletions: 0},
+			},
+		},
+	}
+	return server
+}
+
+func TestGetStats(t *testing.T) {
+	server := newTestServer(t)
+	s := newTestSnapshot(server, Options{})
+
+	if err := getStats(&s); err != nil {
+		t.Fatal(err)
+	}
+
+	if *s._name != "The Octocat" {
+		t.Errorf("name = %q, want %q", *s._name, "The Octocat")
+	}
+	// Forks are not returned for owned repos and external repos are excluded by default
+	if *s._stargazers != 13 {
+		t.Errorf("stargazers = %d, want 13", *s._stargazers)
+	}
+	if *s._forks != 3 {
+		t.Errorf("forks = %d, want 3", *s._forks)
+	}
+	if len(s._repos) != 2 {
+		t.Errorf("repos = %d, want 2", len(s._repos))
+	}
+
+	// A second call is served from the snapshot
+	if _, err := GetStargazers(&s); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.Requests("repositories"); got != 1 {
+		t.Errorf("repositories requested %d times, want 1", got)
+	}
+}
+
+func TestGetStatsFilters(t *testing.T) {
+	server := newTestServer(t)
+	server.Repos[2].External = true
+	s := newTestSnapshot(server, Options{
+		IncludeExternalRepos: true,
+		ExcludedRepos:        map[string]struct{}{"octocat/spoon-knife": {}},
+	})
+
+	repos, err := GetRepos(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"octocat/hello-world", "github/docs"} {
+		if _, ok := repos[want]; !ok {
+			t.Errorf("repos is missing %s", want)
+		}
+	}
+	for _, unwanted := range []string{"octocat/spoon-knife", "octocat/forked"} {
+		if _, ok := repos[unwanted]; ok {
+			t.Errorf("repos contains %s", unwanted)
+		}
+	}
+
+	// The forked external repo is counted once forks are included
+	s = newTestSnapshot(server, Options{IncludeExternalRepos: true, IncludeForkedRepos: true})
+	stars, err := GetStargazers(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stars != 1113 {
+		t.Errorf("stargazers = %d, want 1113", stars)
+	}
+}
+
+func TestGetStatsFailure(t *testing.T) {
+	server := newTestServer(t)
+	s := newTestSnapshot(server, Options{})
+	server.Close()
+
+	_, err := GetName(&s)
+	if err == nil {
+		t.Fatal("expected an error from a closed server")
+	}
+	if !IsFatal(err) {
+		t.Errorf("repositories error should be fatal: %v", err)
+	}
+	if s._repos != nil || s._stargazers != nil {
+		t.Error("partial stats were kept after a failure")
+	}
+}
+
+func TestParseRepoLanguages(t *testing.T) {
+	s := Snapshot{
+		excludedLangs: map[string]struct{}{"html": {}},
+		_languages:    make(map[string]*helpers.LangInfo),
+	}
+
+	var repo RepoWithLanguages
+	repo.Languages.Edges = make([]LanguageEdge, 3)
+	repo.Languages.Edges[0].Size = 300
+	repo.Languages.Edges[0].Node.Name = "Go"
+	repo.Languages.Edges[0].Node.Color = "#00ADD8"
+	repo.Languages.Edges[1].Size = 100
+	repo.Languages.Edges[1].Node.Name = "HTML"
+	repo.Languages.Edges[2].Size = 50
+	repo.Languages.Edges[2].Node.Name = "Shell"
+
+	parseRepoLanguages(&s, &repo)
+	parseRepoLanguages(&s, &repo)
+
+	if _, ok := s._languages["HTML"]; ok {
+		t.Error("excluded language HTML was counted")
+	}
+
+	goLang := s._languages["Go"]
+	if goLang == nil || goLang.Size != 600 || goLang.Occurrences != 2 || goLang.Colour != "#00ADD8" {
+		t.Errorf("Go = %+v, want size 600 in 2 repos", goLang)
+	}
+
+	shell := s._languages["Shell"]
+	if shell == nil || shell.Colour != "#000000" {
+		t.Errorf("Shell = %+v, want the default colour", shell)
+	}
+}
+
+func TestGetLanguagesProportions(t *testing.T) {
+	server := newTestServer(t)
+	s := newTestSnapshot(server, Options{})
+
+	languages, err := GetLanguages(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 400 of Go, 100 of HTML and 50 of Shell
+	if got := languages["Go"].Prop; fmt.Sprintf("%.2f", got) != "72.73" {
+		t.Errorf("Go proportion = %.2f, want 72.73", got)
+	}
+	if got := languages["Go"].Occurrences; got != 2 {
+		t.Errorf("Go occurrences = %d, want 2", got)
+	}
+}
+
+func TestGetLinesChanged(t *testing.T) {
+	server := newTestServer(t)
+	s := newTestSnapshot(server, Options{})
+
+	lines, err := GetLinesChanged(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the user's own commits count: 5+1 + 10+2 in hello-world and 4+4 in spoon-knife
+	if lines != 26 {
+		t.Errorf("lines changed = %d, want 26", lines)
+	}
+
+	repoLines, err := GetRepoLinesChanged(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := repoLines["octocat/hello-world"]; got != [2]int{15, 3} {
+		t.Errorf("hello-world lines = %v, want [15 3]", got)
+	}
+}
+
+func TestGetLinesChangedIncremental(t *testing.T) {
+	server := newTestServer(t)
+	cache := NewLinesCache("octocat")
+
+	s := newTestSnapshot(server, Options{})
+	SetLinesCache(&s, cache)
+	if _, err := GetLinesChanged(&s); err != nil {
+		t.Fatal(err)
+	}
+
+	// Push a new commit, then count again from the cache
+	repo := &server.Repos[0]
+	repo.Commits = append([]githubtest.Commit{{Oid: "c4", Author: "octocat", Additions: 1, Deletions: 1}}, repo.Commits...)
+	server.PageSize = 1
+	before := server.Requests("history")
+
+	s = newTestSnapshot(server, Options{})
+	SetLinesCache(&s, cache)
+	lines, err := GetLinesChanged(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines != 28 {
+		t.Errorf("lines changed = %d, want 28", lines)
+	}
+	// Two pages of hello-world up to the cached head and one page of spoon-knife
+	if got := server.Requests("history") - before; got != 3 {
+		t.Errorf("history requested %d times, want 3", got)
+	}
+
+	// Rewriting the history falls back to a full count
+	repo.Commits = []githubtest.Commit{{Oid: "r1", Author: "octocat", Additions: 2, Deletions: 0}}
+	s = newTestSnapshot(server, Options{})
+	SetLinesCache(&s, cache)
+	if lines, err = GetLinesChanged(&s); err != nil {
+		t.Fatal(err)
+	}
+	if lines != 10 {
+		t.Errorf("lines changed after a force-push = %d, want 10", lines)
+	}
+}
+
+func TestGetLinesChangedMergedBranch(t *testing.T) {
+	server := newTestServer(t)
+	cache := NewLinesCache("octocat")
+
+	s := newTestSnapshot(server, Options{})
+	SetLinesCache(&s, cache)
+	if _, err := GetLinesChanged(&s); err != nil {
+		t.Fatal(err)
+	}
+
+	// A branch whose commits are older than the cached head c3 is merged, so they are listed after it
+	repo := &server.Repos[0]
+	repo.Commits = []githubtest.Commit{
+		{Oid: "m1", Author: "octocat", Merge: true},
+		repo.Commits[0],
+		{Oid: "b1", Author: "octocat", Additions: 7, Deletions: 3},
+		repo.Commits[1],
+		repo.Commits[2],
+	}
+
+	s = newTestSnapshot(server, Options{})
+	SetLinesCache(&s, cache)
+	lines, err := GetLinesChanged(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines != 36 {
+		t.Errorf("lines changed after a merge = %d, want 36", lines)
+	}
+	if cursor, _ := cache.Get("octocat/hello-world"); cursor.HeadOID != "m1" || cursor.Additions != 22 || cursor.Deletions != 6 {
+		t.Errorf("cursor = %+v, want m1 with the full count", cursor)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	server := newTestServer(t)
+	server.PageSize = 1
+	for i := range 3 {
+		server.Repos = append(server.Repos, githubtest.Repo{
+			NameWithOwner: fmt.Sprintf("octocat/extra-%d", i),
+			Stars:         1,
+		})
+	}
+	s := newTestSnapshot(server, Options{IncludeExternalRepos: true})
+
+	repos, err := GetRepos(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 6 {
+		t.Errorf("repos = %d, want 6", len(repos))
+	}
+	// Owned and external repos are paged together until both run out
+	if got := server.Requests("repositories"); got != 5 {
+		t.Errorf("repositories requested %d times, want 5", got)
+	}
+
+	lines, err := GetLinesChanged(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines != 27 {
+		t.Errorf("lines changed = %d, want 27", lines)
+	}
+}
+
+func TestGetContributions(t *testing.T) {
+	server := newTestServer(t)
+	server.MergedPullRequests = 4
+	server.ClosedIssues = 2
+	server.Years[2024] = githubtest.Year{
+		PullRequests: 5,
+		Issues:       3,
+		Reviews:      1,
+		Days: []githubtest.Day{
+			{Date: "2024-12-30", Count: 2},
+			{Date: "2024-12-31", Count: 1},
+		},
+	}
+	server.Years[2025] = githubtest.Year{
+		PullRequests: 1,
+		Days: []githubtest.Day{
+			{Date: "2025-01-01", Count: 4},
+		},
+	}
+	s := newTestSnapshot(server, Options{})
+
+	contributions, err := GetContributions(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contributions != 7 {
+		t.Errorf("contributions = %d, want 7", contributions)
+	}
+
+	activity, err := GetActivity(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Activity{PullRequests: 6, MergedPullRequests: 4, Issues: 3, ClosedIssues: 2, Reviews: 1}
+	if activity != want {
+		t.Errorf("activity = %+v, want %+v", activity, want)
+	}
+
+	days, err := GetContributionCalendar(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 3 || days[0].Date != "2024-12-30" || days[2].Date != "2025-01-01" {
+		t.Errorf("calendar = %+v, want 3 days in order", days)
+	}
+}
+
+// TestExportContributions checks that the export carries the real contribution total, not a placeholder cached before the calendar was queried.
+func TestExportContributions(t *testing.T) {
+	server := newTestServer(t)
+	server.Years[2024] = githubtest.Year{Days: []githubtest.Day{{Date: "2024-05-01", Count: 3}}}
+	server.Years[2025] = githubtest.Year{Days: []githubtest.Day{{Date: "2025-01-01", Count: 4}}}
+	s := newTestSnapshot(server, Options{})
+
+	export, err := NewExport(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if export.Totals.Contributions != 7 {
+		t.Errorf("exported contributions = %d, want 7", export.Totals.Contributions)
+	}
+	if contributions, err := GetContributions(&s); err != nil || contributions != 7 {
+		t.Errorf("contributions after the export = %d, %v, want 7", contributions, err)
+	}
+}
+
+func TestGetViews(t *testing.T) {
+	server := newTestServer(t)
+	s := newTestSnapshot(server, Options{})
+
+	// Repos without traffic access are skipped
+	views, err := GetViews(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if views != 7 {
+		t.Errorf("views = %d, want 7", views)
+	}
+}
+
+func TestPublicUser(t *testing.T) {
+	server := newTestServer(t)
+	server.Repos[1].Private = true
+	server.Years[2024] = githubtest.Year{
+		PullRequests: 2,
+		Days:         []githubtest.Day{{Date: "2024-05-01", Count: 3}},
+	}
+	s := newTestSnapshot(server, Options{Public: true, IncludeExternalRepos: true})
+
+	export, err := NewExport(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Private repos are left out, while the public ones are counted as for the viewer
+	var repos []string
+	for _, repo := range export.Repos {
+		repos = append(repos, repo.NameWithOwner)
+	}
+	if want := []string{"github/docs", "octocat/hello-world"}; !reflect.DeepEqual(repos, want) {
+		t.Errorf("repos = %v, want %v", repos, want)
+	}
+	if export.Name != "The Octocat" || export.Totals.Contributions != 3 || export.Totals.PullRequests != 2 {
+		t.Errorf("name, contributions, pull requests = %q, %d, %d, want The Octocat, 3, 2", export.Name, export.Totals.Contributions, export.Totals.PullRequests)
+	}
+	if export.Totals.LinesChanged != 19 {
+		t.Errorf("lines changed = %d, want 19", export.Totals.LinesChanged)
+	}
+
+	// Traffic views need push access, so they are not even requested
+	if !reflect.DeepEqual(export.Unavailable, []string{"views"}) {
+		t.Errorf("unavailable = %v, want [views]", export.Unavailable)
+	}
+	if got := server.Requests("views"); got != 0 {
+		t.Errorf("views requested %d times, want 0", got)
+	}
+
+	// The avatar is only downloaded once it is asked for
+	if got := server.Requests("avatars"); got != 0 {
+		t.Errorf("avatars requested %d times before GetAvatar, want 0", got)
+	}
+	for range 2 {
+		avatar, err := GetAvatar(&s)
+		if want := "data:image/png;base64," + base64.StdEncoding.EncodeToString(githubtest.Avatar); err != nil || avatar != want {
+			t.Errorf("avatar = %q, %v, want %q", avatar, err, want)
+		}
+	}
+	if got := server.Requests("avatars"); got != 1 {
+		t.Errorf("avatars requested %d times, want 1", got)
+	}
+
+	s = newTestSnapshot(server, Options{Public: true})
+	s.user = "nobody"
+	if _, err := GetRepos(&s); !IsFatal(err) {
+		t.Errorf("repos error = %v, want a fatal error for an unknown login", err)
+	}
+}
+
+func TestGetProfileViews(t *testing.T) {
+	server := newTestServer(t)
+	server.ProfileViews = 1500
+	s := newTestSnapshot(server, Options{IncludeProfileViews: true})
+
+	views, err := GetProfileViews(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if views != 1500 {
+		t.Errorf("profile views = %d, want 1500", views)
+	}
+
+	server.FailProfileViews = true
+	s = newTestSnapshot(server, Options{IncludeProfileViews: true})
+	if _, err := GetProfileViews(&s); err == nil || IsFatal(err) {
+		t.Errorf("profile views error = %v, want a degraded error", err)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	server := newTestServer(t)
+	server.ProfileViews = 42
+	dir := t.TempDir()
+
+	s := newTestSnapshot(server, Options{
+		IncludeProfileViews: true,
+		Transport:           &helpers.RecordTransport{Dir: dir, Token: server.Token, Transport: http.DefaultTransport},
+	})
+	recorded, err := NewExport(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	s = newTestSnapshot(server, Options{
+		IncludeProfileViews: true,
+		Transport:           &helpers.ReplayTransport{Dir: dir},
+	})
+	replayed, err := NewExport(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayed.GeneratedAt = recorded.GeneratedAt
+	if !reflect.DeepEqual(recorded, replayed) {
+		t.Errorf("replayed export differs from the recorded one:\n%+v\n%+v", replayed, recorded)
+	}
+}
@@ -0,0 +1,103 @@
+package snapshot
+
+import (
+	"time"
+)
+
+const calendarDateLayout = "2006-01-02"
+
+// Streak is a run of consecutive days with at least one contribution.
+// Start and End are YYYY-MM-DD dates and are empty for a streak of length 0.
+type Streak struct {
+	Length int    `json:"length"`
+	Start  string `json:"start,omitempty"`
+	End    string `json:"end,omitempty"`
+}
+
+type Streaks struct {
+	Current    Streak `json:"current"`
+	Longest    Streak `json:"longest"`
+	ActiveDays int    `json:"activeDays"`
+}
+
+// computeStreaks walks the sorted contribution calendar and finds the current and longest streaks.
+// Days after today are ignored. A current streak is still ongoing if its last day is today or yesterday,
+// since today's contributions may not have been made yet.
+func computeStreaks(days []ContributionDay, today time.Time) Streaks {
+	todayDate := today.UTC().Format(calendarDateLayout)
+	yesterdayDate := today.UTC().AddDate(0, 0, -1).Format(calendarDateLayout)
+
+	var streaks Streaks
+	var run, latest Streak
+	var previous time.Time
+
+	for _, day := range days {
+		if day.Date > todayDate {
+			break
+		}
+
+		date, err := time.Parse(calendarDateLayout, day.Date)
+		if err != nil {
+			continue
+		}
+
+		// Years without contributions are missing from the calendar, so a gap in dates also breaks a streak
+		if day.Count == 0 || (run.Length > 0 && !date.Equal(previous.AddDate(0, 0, 1))) {
+			run = Streak{}
+		}
+		previous = date
+
+		if day.Count == 0 {
+			continue
+		}
+
+		streaks.ActiveDays++
+		if run.Length == 0 {
+			run.Start = day.Date
+		}
+		run.Length++
+		run.End = day.Date
+		latest = run
+
+		if run.Length > streaks.Longest.Length {
+			streaks.Longest = run
+		}
+	}
+
+	if latest.End == todayDate || latest.End == yesterdayDate {
+		streaks.Current = latest
+	}
+
+	return streaks
+}
+
+// GetStreaks returns the current and longest contribution streaks and the number of days with contributions across all contribution years.
+func GetStreaks(self *Snapshot) (Streaks, error) {
+	if self._streaks != nil {
+		return *self._streaks, nil
+	}
+
+	days, err := GetContributionCalendar(self)
+	if err != nil {
+		return Streaks{}, err
+	}
+
+	streaks := computeStreaks(days, time.Now())
+	self._streaks = &streaks
+	return streaks, nil
+}
+
+func GetCurrentStreak(self *Snapshot) (Streak, error) {
+	streaks, err := GetStreaks(self)
+	return streaks.Current, err
+}
+
+func GetLongestStreak(self *Snapshot) (Streak, error) {
+	streaks, err := GetStreaks(self)
+	return streaks.Longest, err
+}
+
+func GetActiveDays(self *Snapshot) (int, error) {
+	streaks, err := GetStreaks(self)
+	return streaks.ActiveDays, err
+}
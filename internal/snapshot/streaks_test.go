@@ -0,0 +1,84 @@
+package snapshot
+
+import (
+	"testing"
+	"time"
+)
+
+func calendar(counts map[string]int, from string, to string) []ContributionDay {
+	var days []ContributionDay
+	start, _ := time.Parse(calendarDateLayout, from)
+	end, _ := time.Parse(calendarDateLayout, to)
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		day := date.Format(calendarDateLayout)
+		days = append(days, ContributionDay{Date: day, Count: counts[day]})
+	}
+	return days
+}
+
+func TestComputeStreaks(t *testing.T) {
+	today := time.Date(2025, time.January, 31, 12, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		name    string
+		days    []ContributionDay
+		current Streak
+		longest Streak
+		active  int
+	}{
+		{
+			name:    "zero-count day",
+			days:    calendar(map[string]int{"2025-01-27": 1, "2025-01-28": 2, "2025-01-30": 1, "2025-01-31": 3}, "2025-01-26", "2025-01-31"),
+			current: Streak{Length: 2, Start: "2025-01-30", End: "2025-01-31"},
+			longest: Streak{Length: 2, Start: "2025-01-27", End: "2025-01-28"},
+			active:  4,
+		},
+		{
+			// Days missing from the calendar, e.g. a year without contributions, break a streak too
+			name: "gap day",
+			days: []ContributionDay{
+				{Date: "2025-01-27", Count: 1},
+				{Date: "2025-01-28", Count: 1},
+				{Date: "2025-01-30", Count: 1},
+			},
+			current: Streak{Length: 1, Start: "2025-01-30", End: "2025-01-30"},
+			longest: Streak{Length: 2, Start: "2025-01-27", End: "2025-01-28"},
+			active:  3,
+		},
+		{
+			name:    "ending yesterday",
+			days:    calendar(map[string]int{"2025-01-29": 1, "2025-01-30": 1}, "2025-01-28", "2025-01-31"),
+			current: Streak{Length: 2, Start: "2025-01-29", End: "2025-01-30"},
+			longest: Streak{Length: 2, Start: "2025-01-29", End: "2025-01-30"},
+			active:  2,
+		},
+		{
+			name:    "ending two days ago",
+			days:    calendar(map[string]int{"2025-01-28": 1, "2025-01-29": 1}, "2025-01-27", "2025-01-31"),
+			longest: Streak{Length: 2, Start: "2025-01-28", End: "2025-01-29"},
+			active:  2,
+		},
+		{
+			// The earliest of the longest streaks is kept
+			name:    "tied longest",
+			days:    calendar(map[string]int{"2025-01-20": 1, "2025-01-21": 1, "2025-01-23": 1, "2025-01-24": 1}, "2025-01-20", "2025-01-31"),
+			longest: Streak{Length: 2, Start: "2025-01-20", End: "2025-01-21"},
+			active:  4,
+		},
+		{
+			name:    "days after today",
+			days:    calendar(map[string]int{"2025-01-31": 1, "2025-02-01": 1}, "2025-01-31", "2025-02-01"),
+			current: Streak{Length: 1, Start: "2025-01-31", End: "2025-01-31"},
+			longest: Streak{Length: 1, Start: "2025-01-31", End: "2025-01-31"},
+			active:  1,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			streaks := computeStreaks(tt.days, today)
+			want := Streaks{Current: tt.current, Longest: tt.longest, ActiveDays: tt.active}
+			if streaks != want {
+				t.Errorf("streaks = %+v, want %+v", streaks, want)
+			}
+		})
+	}
+}
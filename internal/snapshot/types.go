@@ -13,27 +13,69 @@ type RepoBase struct {
 	Stargazers    struct {
 		TotalCount int
 	}
-	ForkCount int
+	ForkCount       int
+	PrimaryLanguage struct {
+		Name  string
+		Color string
+	}
 }
 
 type RepoWithLanguages struct {
 	RepoBase
 
 	Languages struct {
-		Edges []struct {
-			Size int
-			Node struct {
-				Name  string
-				Color string
-			}
-		}
+		Edges []LanguageEdge
 	} `graphql:"languages(first: 10, orderBy: {field: SIZE, direction: DESC})"`
 }
 
+// LanguageEdge is the size of a single language within a repo.
+type LanguageEdge struct {
+	Size int
+	Node struct {
+		Name  string
+		Color string
+	}
+}
+
+// RepoConnection is a page of repositories.
+type RepoConnection struct {
+	PageInfo struct {
+		HasNextPage bool
+		EndCursor   string
+	}
+	Nodes []RepoWithLanguages
+}
+
 type ReposOverviewQuery struct {
 	Viewer struct {
-		Login string
-		Name  string
+		Login     string
+		Name      string
+		AvatarUrl string `graphql:"avatarUrl(size: 96)"`
+
+		Repositories              RepoConnection `graphql:"repositories(first: 100, isFork: false, after: $repoCursor)"`
+		RepositoriesContributedTo RepoConnection `graphql:"repositoriesContributedTo(first: 100, includeUserRepositories: false, after: $contribCursor, contributionTypes: [COMMIT, PULL_REQUEST, REPOSITORY, PULL_REQUEST_REVIEW])"`
+	} `graphql:"viewer"`
+}
+
+// PublicReposOverviewQuery is ReposOverviewQuery for any user, limited to the repositories everyone can see.
+type PublicReposOverviewQuery struct {
+	User struct {
+		Login     string
+		Name      string
+		AvatarUrl string `graphql:"avatarUrl(size: 96)"`
+
+		Repositories              RepoConnection `graphql:"repositories(first: 100, isFork: false, privacy: PUBLIC, after: $repoCursor)"`
+		RepositoriesContributedTo RepoConnection `graphql:"repositoriesContributedTo(first: 100, includeUserRepositories: false, privacy: PUBLIC, after: $contribCursor, contributionTypes: [COMMIT, PULL_REQUEST, REPOSITORY, PULL_REQUEST_REVIEW])"`
+	} `graphql:"user(login: $login)"`
+}
+
+// OrgReposQuery lists every repository of an organization, forks included.
+type OrgReposQuery struct {
+	Organization struct {
+		Login       string
+		Name        string
+		Description string
+		AvatarUrl   string `graphql:"avatarUrl(size: 96)"`
 
 		Repositories struct {
 			PageInfo struct {
@@ -41,15 +83,22 @@ type ReposOverviewQuery struct {
 				EndCursor   string
 			}
 			Nodes []RepoWithLanguages
-		} `graphql:"repositories(first: 100, isFork: false, after: $repoCursor)"`
-		RepositoriesContributedTo struct {
-			PageInfo struct {
-				HasNextPage bool
-				EndCursor   string
-			}
-			Nodes []RepoWithLanguages
-		} `graphql:"repositoriesContributedTo(first: 100, includeUserRepositories: false, after: $contribCursor, contributionTypes: [COMMIT, PULL_REQUEST, REPOSITORY, PULL_REQUEST_REVIEW])"`
-	} `graphql:"viewer"`
+		} `graphql:"repositories(first: 100, after: $repoCursor)"`
+	} `graphql:"organization(login: $login)"`
+}
+
+// Organization describes the organization an organization snapshot is generated for.
+type Organization struct {
+	Login       string `json:"login"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Avatar      string `json:"avatar,omitempty"` // Data URI of the avatar, empty if it could not be fetched
+}
+
+// ContributorCount is a contributor to the counted repos with their number of commits across all of them.
+type ContributorCount struct {
+	Login         string `json:"login"`
+	Contributions int    `json:"contributions"`
 }
 
 type CommitStatsQuery struct {
@@ -63,6 +112,7 @@ type CommitStatsQuery struct {
 							EndCursor   graphql.String
 						}
 						Nodes []struct {
+							Oid       string
 							Additions int
 							Deletions int
 							Author    struct {
@@ -70,6 +120,9 @@ type CommitStatsQuery struct {
 									Login string
 								}
 							}
+							Parents struct {
+								TotalCount int
+							} `graphql:"parents(first: 1)"`
 						}
 					} `graphql:"history(first: 100, after: $commitCursor)"`
 				} `graphql:"... on Commit"`
@@ -78,33 +131,90 @@ type CommitStatsQuery struct {
 	} `graphql:"repository(owner: $owner, name: $name)"`
 }
 
-type ContributionYearsQuery struct {
-	Viewer struct {
-		ContributionsCollection struct {
-			ContributionYears []int
-		}
+// ContributionYears lists the years a user has contributed in, most recent first.
+type ContributionYears struct {
+	ContributionsCollection struct {
+		ContributionYears []int
 	}
 }
 
+type ContributionYearsQuery struct {
+	Viewer ContributionYears
+}
+
+// PublicContributionYearsQuery is ContributionYearsQuery for any user.
+type PublicContributionYearsQuery struct {
+	User ContributionYears `graphql:"user(login: $login)"`
+}
+
+// ContributionDay is a single day of a user's contribution calendar.
+type ContributionDay struct {
+	Date  string `json:"date"`              // YYYY-MM-DD
+	Count int    `json:"contributionCount"` // Number of contributions made on that day
+	Level string `json:"contributionLevel"` // NONE, FIRST_QUARTILE, SECOND_QUARTILE, THIRD_QUARTILE or FOURTH_QUARTILE
+}
+
+type ContributionYear struct {
+	TotalPullRequestContributions       int `json:"totalPullRequestContributions"`
+	TotalIssueContributions             int `json:"totalIssueContributions"`
+	TotalPullRequestReviewContributions int `json:"totalPullRequestReviewContributions"`
+	ContributionCalendar                struct {
+		TotalContributions int `json:"totalContributions"`
+		Weeks              []struct {
+			ContributionDays []ContributionDay `json:"contributionDays"`
+		} `json:"weeks"`
+	} `json:"contributionCalendar"`
+}
+
+// Activity counts the user's pull requests, issues and code reviews across all years.
+type Activity struct {
+	PullRequests       int // Pull requests opened
+	MergedPullRequests int // Pull requests authored by the user that were merged
+	Issues             int // Issues opened
+	ClosedIssues       int // Issues authored by the user that were closed
+	Reviews            int // Pull request reviews given
+}
+
 type Snapshot struct {
 	user                 string
+	organization         string
+	public               bool
 	accessToken          string
+	endpoints            helpers.Endpoints
 	client               *http.Client
 	queryClient          *graphql.Client
+	plainClient          *http.Client // Downloads avatars and profile views, without the token or the API rate limiter
 	excludedRepos        map[string]struct{}
 	excludedLangs        map[string]struct{}
 	includeForkedRepos   bool
 	includeExternalRepos bool
 	IncludeProfileViews  bool
+	workers              int
+	linesCache           *LinesCache
 	_name                *string
+	_avatarUrl           string
+	_avatar              *string
 	_stargazers          *int
 	_forks               *int
 	_totalContributions  *int
+	_contributionDays    []ContributionDay
+	_streaks             *Streaks
+	_activity            *Activity
 	_languages           map[string]*helpers.LangInfo
 	_repos               map[string]RepoWithLanguages
 	_linesChanged        *[2]int // [0]: Added, [1]: Deleted
 	_views               *int
+	_repoLinesChanged    map[string][2]int // [0]: Added, [1]: Deleted
+	_repoViews           map[string]int
 	_profileViews        *int
+	_organization        *Organization
+	_contributors        []ContributorCount
+	_statsErr            error
+	_contributionsErr    error
+	_linesChangedErr     error
+	_profileViewsErr     error
+	_viewsErr            error
+	_contributorsErr     error
 }
 
 type Contributor struct {
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"snapshot/internal/render"
+)
+
+func runLeaderboard(args []string) error {
+	var common commonFlags
+	var fetch fetchFlags
+	var users loginFlags
+	fs := newFlagSet("leaderboard", "Rank several users by their public data and render the team leaderboard card into the output directory.")
+	common.register(fs)
+	fetch.register(fs)
+	users.register(fs)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	cfg, logins, err := loadUsers(common, fetch, users)
+	if err != nil {
+		return err
+	}
+	if err := validateCards(cfg); err != nil {
+		return err
+	}
+	if err := validateOutputDir(cfg.Output.Dir); err != nil {
+		return err
+	}
+
+	api, err := newAPIClient(cfg, common, fetch)
+	if err != nil {
+		return err
+	}
+
+	// A failing user is left off the leaderboard, the others are still ranked
+	var members []render.Member
+	var errs []error
+	today := time.Now().UTC()
+	for i, login := range logins {
+		log.Printf("Fetching %s (%d/%d)", login, i+1, len(logins))
+
+		s, _, err := api.fetchSnapshot(userConfig(cfg, login))
+		if err == nil {
+			var member render.Member
+			if member, err = render.NewMember(s, today); err == nil {
+				members = append(members, member)
+				continue
+			}
+		}
+		log.Printf("Failed to fetch %s: %v", login, err)
+		errs = append(errs, fmt.Errorf("%s: %w", login, err))
+	}
+
+	leaderboard := render.NewLeaderboard(members, cfg.Cards.Leaderboard.Metrics, cfg.Cards.Leaderboard.Count)
+	data := render.Data{
+		Name:        cfg.Cards.Leaderboard.Title,
+		Theme:       cfg.Theme,
+		Leaderboard: &leaderboard,
+	}
+	rendered, err := render.RenderDir(cfg.Output.TemplatesDir, cfg.Output.Dir, cfg.Cards.Only, data)
+	for _, path := range rendered {
+		log.Printf("Generated %s", path)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("GitHub API usage: %s", api.limiter.Summary())
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d users are missing from the leaderboard: %w", len(errs), len(logins), errors.Join(errs...))
+	}
+	return nil
+}
@@ -1,114 +1,81 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"os"
-	"snapshot/internal/helpers"
-	"snapshot/internal/snapshot"
-	"strings"
-
-	"github.com/dustin/go-humanize"
 )
 
-func validateOutputDir() error {
-	dir := "./generated"
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		err := os.MkdirAll(dir, 0755)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func check(e error) {
-	if e != nil {
-		panic(e)
-	}
-}
+const usage = `Usage: snapshot <command> [flags]
 
-func generateOverview(s *snapshot.Snapshot) {
-	dat, err := os.ReadFile("templates/overview.svg")
-	check(err)
-	output := strings.Replace(string(dat), "{{ name }}", snapshot.GetName(s), 1)
+Commands:
+  generate         fetch the statistics, render every card and record the history (default)
+  fetch            fetch the statistics and write snapshot.json only
+  render           render the cards from a saved snapshot.json without calling the GitHub API
+  batch            generate the cards of several users from their public data into <output>/<login>
+  leaderboard      rank several users by their public data on the team leaderboard card
+  validate-config  check the configuration file and environment, then exit
 
-	output = strings.Replace(output, "{{ stars }}", humanize.Comma(int64(snapshot.GetStargazers(s))), 1)
-	output = strings.Replace(output, "{{ forks }}", humanize.Comma(int64(snapshot.GetForks(s))), 1)
-	output = strings.Replace(output, "{{ contributions }}", humanize.Comma(int64(snapshot.GetContributions(s))), 1)
-	output = strings.Replace(output, "{{ lines_changed }}", humanize.Comma(snapshot.GetLinesChanged(s)), 1)
-	output = strings.Replace(output, "{{ repos }}", humanize.Comma(int64(len(snapshot.GetRepos(s)))), 1)
-	output = strings.Replace(output, "{{ views }}", humanize.Comma(int64(snapshot.GetViews(s))), 1)
+Run snapshot <command> -h for the flags of a command.
+`
 
-	if s.IncludeProfileViews {
-		output = strings.Replace(output, "{{ profile_views }}", humanize.Comma(int64(snapshot.GetProfileViews(s))), 1)
-		output = strings.Replace(output, ` class="hide-profile-views"`, "", 1)
-		output = strings.Replace(output, ` height="210"`, ` height="234"`, 1)
-	}
+type command struct {
+	run func(args []string) error
+}
 
-	overview := []byte(output)
-	werr := os.WriteFile("generated/overview.svg", overview, 0644)
-	check(werr)
+var commands = map[string]command{
+	"generate":        {runGenerate},
+	"fetch":           {runFetch},
+	"render":          {runRender},
+	"batch":           {runBatch},
+	"leaderboard":     {runLeaderboard},
+	"validate-config": {runValidateConfig},
 }
 
-func generateLanguages(s *snapshot.Snapshot) {
-	const templatePath = "templates/languages.svg"
-	const outputPath = "generated/languages.svg"
+// errUsage is returned when the arguments are invalid and the usage has already been printed.
+var errUsage = errors.New("invalid usage")
 
-	dat, err := os.ReadFile(templatePath)
-	check(err)
+func main() {
+	args := os.Args[1:]
 
-	progress := ""
-	langList := ""
-	sortedLanguages := helpers.SortLanguages(snapshot.GetLanguages(s))
-	delay := 50
-	for _, entry := range sortedLanguages {
-		progress += helpers.BuildProgressHTML(entry)
-		langList += helpers.BuildLangListHTML(entry, delay)
-		delay += 50
+	// Running without a command, or with flags only, keeps generating everything as before
+	name := "generate"
+	if len(args) > 0 && !isFlag(args[0]) {
+		name, args = args[0], args[1:]
 	}
 
-	output := strings.Replace(string(dat), "{{ progress }}", progress, 1)
-	output = strings.Replace(output, "{{ lang_list }}", langList, 1)
+	if name == "help" {
+		fmt.Print(usage)
+		return
+	}
 
-	if s.IncludeProfileViews {
-		output = strings.Replace(output, ` height="210"`, ` height="234"`, 1)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
 	}
 
-	overview := []byte(output)
-	werr := os.WriteFile(outputPath, overview, 0644)
-	check(werr)
+	if err := cmd.run(args); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		// Errors are printed even in quiet mode, which only silences the log
+		fmt.Fprintf(os.Stderr, "snapshot %s: %v\n", name, err)
+		os.Exit(1)
+	}
 }
 
-func main() {
-	validateOutputDir()
-	helpers.ReadEnvFile()
-
-	accessToken, err1 := helpers.GetRequiredEnv("ACCESS_TOKEN")
-	user, err2 := helpers.GetRequiredEnv("GITHUB_ACTOR")
+func isFlag(arg string) bool {
+	return len(arg) > 1 && arg[0] == '-'
+}
 
-	if err1 != nil || err2 != nil {
-		log.Fatal("Failed loading required ENV")
+// setVerbosity silences the log when quiet, otherwise logs to stderr.
+func setVerbosity(quiet bool) {
+	log.SetOutput(os.Stderr)
+	if quiet {
+		log.SetOutput(io.Discard)
 	}
-
-	excludedRepos := helpers.GetListEnv("EXCLUDED_REPOS")
-	excludedLangs := helpers.GetListEnv("EXCLUDED_LANGS")
-
-	includeForkedRepos := helpers.GetBooleanEnv("INCLUDE_FORKED_REPOS", false)
-	includeExternalRepos := helpers.GetBooleanEnv("INCLUDE_EXTERNAL_REPOS", false)
-	includeProfileViews := helpers.GetBooleanEnv("INCLUDE_PROFILE_VIEWS", false)
-
-	s := snapshot.NewSnapshot(
-		user,
-		accessToken,
-		excludedRepos,
-		excludedLangs,
-		includeForkedRepos,
-		includeExternalRepos,
-		includeProfileViews,
-	)
-
-	snapshot.GetRepos(&s)
-	snapshot.GetProfileViews(&s)
-	generateOverview(&s)
-	generateLanguages(&s)
 }
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"snapshot/internal/githubtest"
+)
+
+// overrides are every environment variable the configuration reads, see internal/config.
+var overrides = []string{
+	"CONFIG_FILE", "ACCESS_TOKEN", "GITHUB_ACTOR", "ORGANIZATION", "PUBLIC_ONLY", "THEME", "API_URL", "GRAPHQL_URL",
+	"EXCLUDED_REPOS", "EXCLUDED_LANGS", "INCLUDE_FORKED_REPOS", "INCLUDE_EXTERNAL_REPOS", "INCLUDE_PROFILE_VIEWS",
+	"OUTPUT_DIR", "TEMPLATES_DIR", "HISTORY_FILE", "LINES_CACHE_FILE", "FIXTURES_DIR",
+	"WORKERS", "LINES_FULL_RESCAN", "RETRY_MAX_ATTEMPTS", "RETRY_BASE_DELAY", "RETRY_MAX_DELAY",
+	"CARDS", "DELTA_WINDOWS", "HEATMAP_YEAR", "TOP_REPOS_METRIC", "TOP_REPOS_COUNT",
+	"LEADERBOARD_TITLE", "LEADERBOARD_METRICS", "LEADERBOARD_COUNT",
+	"BATCH_USERS", "BATCH_USERS_FILE",
+}
+
+// useFakeGitHub unsets every override for the duration of the test, e.g. the ones set in GitHub Actions,
+// then points the commands at the fake server with the given configuration file.
+// It returns the temporary directory holding the configuration and the lines changed cache.
+func useFakeGitHub(t *testing.T, server *githubtest.Server, configYAML string) string {
+	t.Helper()
+	for _, name := range overrides {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "snapshot.yaml")
+	if err := os.WriteFile(configFile, []byte(configYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", configFile)
+	t.Setenv("ACCESS_TOKEN", server.Token)
+	t.Setenv("API_URL", server.URL)
+	t.Setenv("GRAPHQL_URL", server.URL+"/graphql")
+	t.Setenv("LINES_CACHE_FILE", filepath.Join(dir, "cache.json"))
+	t.Setenv("RETRY_MAX_ATTEMPTS", "1")
+	return dir
+}
+
+func TestGenerateThenRender(t *testing.T) {
+	server := githubtest.NewServer(t, "octocat")
+	server.Token = "test-token"
+	server.Name = "The Octocat"
+	server.Repos = []githubtest.Repo{{
+		NameWithOwner: "octocat/hello-world",
+		Stars:         10,
+		Languages:     []githubtest.Language{{Name: "Go", Color: "#00ADD8", Size: 100}},
+		Commits:       []githubtest.Commit{{Oid: "c1", Author: "octocat", Additions: 3, Deletions: 1}},
+		Views:         7,
+	}}
+	today := time.Now().UTC()
+	server.Years[today.Year()] = githubtest.Year{
+		PullRequests: 2,
+		Days: []githubtest.Day{
+			{Date: today.AddDate(0, 0, -1).Format("2006-01-02"), Count: 3, Level: "SECOND_QUARTILE"},
+			{Date: today.Format("2006-01-02"), Count: 1, Level: "FIRST_QUARTILE"},
+		},
+	}
+
+	dir := useFakeGitHub(t, server, "theme: dark\n")
+
+	generated := filepath.Join(dir, "generated")
+	rendered := filepath.Join(dir, "rendered")
+	if err := runValidateConfig([]string{"-config", "snapshot.example.yaml", "-q"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := runGenerate([]string{"-q", "-user", "octocat", "-output", generated}); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	// Rendering from the saved snapshot must not need the API
+	from := filepath.Join(generated, "snapshot.json")
+	if err := runRender([]string{"-q", "-from", from, "-output", rendered}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(generated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		card := entry.Name()
+		if filepath.Ext(card) != ".svg" {
+			continue
+		}
+		want, err := os.ReadFile(filepath.Join(generated, card))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(rendered, card))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s rendered from the saved snapshot differs from the generated one", card)
+		}
+	}
+
+	if err := runRender([]string{"-q", "-from", from, "-user", "hubot", "-output", rendered}); err == nil {
+		t.Error("expected an error rendering another user's snapshot")
+	}
+}
+
+func TestBatch(t *testing.T) {
+	server := githubtest.NewServer(t, "octocat")
+	server.Token = "test-token"
+	server.Repos = []githubtest.Repo{{
+		NameWithOwner: "octocat/hello-world",
+		Stars:         10,
+		Languages:     []githubtest.Language{{Name: "Go", Color: "#00ADD8", Size: 100}},
+		Commits:       []githubtest.Commit{{Oid: "c1", Author: "octocat", Additions: 3, Deletions: 1}},
+	}}
+
+	dir := useFakeGitHub(t, server, "batch:\n  users: [hubot]\n")
+	usersFile := filepath.Join(dir, "users.txt")
+	if err := os.WriteFile(usersFile, []byte("# team\nOctocat\n\nghost\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// -users replaces the configured users, a failing user does not stop the batch but fails the command
+	generated := filepath.Join(dir, "generated")
+	err := runBatch([]string{"-q", "-users", "octocat", "-users-file", usersFile, "-output", generated})
+	if err == nil {
+		t.Error("expected an error for the unknown user")
+	}
+
+	if _, err := os.Stat(filepath.Join(generated, "octocat", "overview.svg")); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(generated, "octocat", "snapshot.json")); err != nil {
+		t.Error(err)
+	}
+
+	dat, err := os.ReadFile(filepath.Join(generated, "index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index batchIndex
+	if err := json.Unmarshal(dat, &index); err != nil {
+		t.Fatal(err)
+	}
+	if index.Succeeded != 1 || index.Failed != 1 || len(index.Users) != 2 {
+		t.Fatalf("index = %+v, want octocat succeeded and ghost failed", index)
+	}
+	if user := index.Users[0]; user.Login != "octocat" || !user.OK || user.Output != "octocat" {
+		t.Errorf("first user = %+v, want octocat generated", user)
+	}
+	if user := index.Users[1]; user.Login != "ghost" || user.OK || user.Error == "" {
+		t.Errorf("second user = %+v, want ghost failed", user)
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	server := githubtest.NewServer(t, "octocat")
+	server.Token = "test-token"
+	server.Name = "The Octocat"
+	server.Repos = []githubtest.Repo{{
+		NameWithOwner: "octocat/hello-world",
+		Commits:       []githubtest.Commit{{Oid: "c1", Author: "octocat", Additions: 3, Deletions: 1}},
+	}}
+	today := time.Now().UTC()
+	server.Years[today.Year()] = githubtest.Year{
+		Days: []githubtest.Day{{Date: today.Format("2006-01-02"), Count: 4, Level: "FIRST_QUARTILE"}},
+	}
+
+	dir := useFakeGitHub(t, server, "cards:\n  leaderboard:\n    title: Octo Team\n    metrics: [linesChanged]\n")
+
+	// The unknown user fails the command, but the others are still ranked
+	generated := filepath.Join(dir, "generated")
+	if err := runLeaderboard([]string{"-q", "-users", "octocat,ghost", "-output", generated}); err == nil {
+		t.Error("expected an error for the unknown user")
+	}
+
+	entries, err := os.ReadDir(generated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "team-leaderboard.svg" {
+		t.Fatalf("generated %v, want only team-leaderboard.svg", entries)
+	}
+	card, err := os.ReadFile(filepath.Join(generated, "team-leaderboard.svg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Octo Team", "Lines changed", "The Octocat", `<td class="value">4</td>`, "data:image/png;base64,"} {
+		if !bytes.Contains(card, []byte(want)) {
+			t.Errorf("leaderboard does not contain %q", want)
+		}
+	}
+	if bytes.Contains(card, []byte("ghost")) {
+		t.Error("leaderboard lists the unknown user")
+	}
+}
+
+func TestReplayKeepsHistory(t *testing.T) {
+	server := githubtest.NewServer(t, "octocat")
+	server.Token = "test-token"
+	server.Repos = []githubtest.Repo{{NameWithOwner: "octocat/hello-world", Stars: 10}}
+
+	dir := useFakeGitHub(t, server, "theme: dark\n")
+
+	generated := filepath.Join(dir, "generated")
+	fixtures := filepath.Join(dir, "fixtures")
+	if err := runGenerate([]string{"-q", "-user", "octocat", "-output", generated, "-record", "-fixtures", fixtures}); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	// Today's real entry must survive a replay of the recorded traffic
+	historyFile := filepath.Join(generated, "history.jsonl")
+	today := time.Now().UTC().Format("2006-01-02")
+	want := []byte(`{"date":"` + today + `","recordedAt":"` + time.Now().UTC().Format(time.RFC3339) + `","metrics":{"stars":999}}` + "\n")
+	if err := os.WriteFile(historyFile, want, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := runGenerate([]string{"-q", "-user", "octocat", "-output", generated, "-replay", "-fixtures", fixtures}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(historyFile); err != nil || !bytes.Equal(got, want) {
+		t.Errorf("history after the replay = %s (%v), want it unchanged", got, err)
+	}
+}